@@ -3,9 +3,14 @@ module github.com/sean-tech/webkit
 go 1.13
 
 require (
+	github.com/alicebob/miniredis/v2 v2.11.4
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/gin-gonic/gin v1.6.2
+	github.com/go-playground/validator/v10 v10.2.0
+	github.com/go-redis/redis/v7 v7.4.1
 	github.com/rcrowley/go-metrics v0.0.0-20200313005456-10cdbea86bc0
 	github.com/sean-tech/gokit v1.0.6
 	github.com/smallnest/rpcx v0.0.0-20200414114925-bff251b691b9
+	go.etcd.io/bbolt v1.3.4
+	golang.org/x/crypto v0.0.0-20191219195013-becbf705a915
 )
@@ -0,0 +1,192 @@
+package serving
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const default_shutdown_timeout = 5 * time.Second
+
+type AppConfig struct {
+	HttpServer 			*HttpServer		`json:"-"`
+	RpcServer 			*RpcServer		`json:"-"`
+	// 优雅关闭等待时长，为0时使用默认5秒
+	ShutdownTimeout 	time.Duration	`json:"shutdown_timeout"`
+	// 启动回调超时，为0时不限制
+	StartTimeout 		time.Duration	`json:"start_timeout"`
+}
+
+/** 生命周期回调函数 **/
+type AppHookFunc func(ctx context.Context) error
+
+/**
+ * 关闭回调，startHooks为注册时已注册的启动回调数，启动失败时仅执行对应启动回调已成功的关闭回调
+ */
+type appStopHook struct {
+	hook 		AppHookFunc
+	startHooks 	int
+}
+
+/**
+ * 应用实例，统一管理http、rpc server的启动关闭及生命周期回调
+ */
+type App struct {
+	config 		AppConfig
+	onStart 	[]AppHookFunc
+	onStop 		[]appStopHook
+	stopOnce 	sync.Once
+	stop 		chan struct{}
+}
+
+/**
+ * 创建应用，http server与rpc server至少需提供一个
+ */
+func NewApp(config AppConfig) (*App, error) {
+	if config.HttpServer == nil && config.RpcServer == nil {
+		return nil, errors.New("app needs at least one of http server or rpc server")
+	}
+	if config.ShutdownTimeout < 0 {
+		return nil, errors.New("app shutdown timeout must not be negative")
+	}
+	if config.StartTimeout < 0 {
+		return nil, errors.New("app start timeout must not be negative")
+	}
+	if config.ShutdownTimeout == 0 {
+		config.ShutdownTimeout = default_shutdown_timeout
+	}
+	return &App{
+		config: config,
+		stop:   make(chan struct{}),
+	}, nil
+}
+
+/**
+ * 注册启动回调，按注册顺序于server启动前执行
+ */
+func (this *App) OnStart(hook AppHookFunc) {
+	this.onStart = append(this.onStart, hook)
+}
+
+/**
+ * 注册关闭回调，按注册逆序于server关闭后执行
+ * 关闭回调归属于其之前最近注册的启动回调，启动失败时仅逆序执行归属启动回调已成功的关闭回调，
+ * 如 OnStart(openDb); OnStop(closeDb); OnStart(openCache); OnStop(closeCache)，openCache失败时仅执行closeDb
+ */
+func (this *App) OnStop(hook AppHookFunc) {
+	this.onStop = append(this.onStop, appStopHook{hook: hook, startHooks: len(this.onStart)})
+}
+
+/**
+ * 运行应用，阻塞直至收到SIGINT/SIGTERM或调用Stop，随后优雅关闭
+ */
+func (this *App) Run() error {
+	if err := this.start(); err != nil {
+		return err
+	}
+	// signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	select {
+	case <- quit:
+	case <- this.stop:
+	}
+	log.Println("Shutdown App ...")
+	err := this.shutdown()
+	log.Println("App exiting")
+	return err
+}
+
+/**
+ * 主动触发关闭，可重复调用
+ */
+func (this *App) Stop() {
+	this.stopOnce.Do(func() {
+		close(this.stop)
+	})
+}
+
+/**
+ * 依次执行启动回调、启动rpc server、启动http server，任一失败即关闭已启动server，逆序执行已成功启动回调对应的关闭回调并返回
+ */
+func (this *App) start() error {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if this.config.StartTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, this.config.StartTimeout)
+	}
+	defer cancel()
+	for i, hook := range this.onStart {
+		if err := hook(ctx); err != nil {
+			this.rollback(i, false)
+			return err
+		}
+	}
+	if this.config.RpcServer != nil {
+		if err := this.config.RpcServer.Start(); err != nil {
+			this.rollback(len(this.onStart), false)
+			return err
+		}
+	}
+	if this.config.HttpServer != nil {
+		if err := this.config.HttpServer.Start(); err != nil {
+			this.rollback(len(this.onStart), this.config.RpcServer != nil)
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+ * 启动失败回滚，关闭已启动的rpc server，逆序执行前started个启动回调成功后注册的关闭回调
+ */
+func (this *App) rollback(started int, rpcStarted bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), this.config.ShutdownTimeout)
+	defer cancel()
+	if rpcStarted {
+		if err := this.config.RpcServer.Shutdown(ctx); err != nil {
+			log.Println("App rollback rpc server:", err)
+		}
+	}
+	for i := len(this.onStop) - 1; i >= 0; i-- {
+		if this.onStop[i].startHooks > started {
+			continue
+		}
+		if err := this.onStop[i].hook(ctx); err != nil {
+			log.Println("App rollback stop hook:", err)
+		}
+	}
+}
+
+/**
+ * 先关闭http server停止接收外部请求，再关闭rpc server，最后逆序执行关闭回调，返回首个错误
+ */
+func (this *App) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), this.config.ShutdownTimeout)
+	defer cancel()
+	var first error
+	if this.config.HttpServer != nil {
+		if err := this.config.HttpServer.Shutdown(ctx); err != nil {
+			first = err
+		}
+	}
+	if this.config.RpcServer != nil {
+		if err := this.config.RpcServer.Shutdown(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	for i := len(this.onStop) - 1; i >= 0; i-- {
+		if err := this.onStop[i].hook(ctx); err != nil {
+			log.Println("App stop hook:", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
@@ -0,0 +1,102 @@
+package serving
+
+import (
+	"context"
+	"errors"
+	"github.com/sean-tech/gokit/logging"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAppLifecycle(t *testing.T) {
+	setupTestLogging()
+	server, err := NewHttpServer(HttpConfig{
+		RunMode:        "test",
+		WorkerId:       0,
+		HttpPort:       8002,
+		ReadTimeout:    60 * time.Second,
+		WriteTimeout:   60 * time.Second,
+		JwtSecret:      "webkit/serving/jwtsecret/token@20200427",
+		JwtIssuer:      "sean.tech/webkit/user",
+		JwtExpiresTime: 36 * time.Hour,
+		Logger:         logging.Logger(),
+		SecretStorage:  NewMemeoryStorage(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := NewApp(AppConfig{
+		HttpServer:      server,
+		ShutdownTimeout: time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	app.OnStart(func(ctx context.Context) error {
+		calls = append(calls, "start db")
+		return nil
+	})
+	app.OnStart(func(ctx context.Context) error {
+		calls = append(calls, "start cache")
+		app.Stop()
+		return nil
+	})
+	app.OnStop(func(ctx context.Context) error {
+		calls = append(calls, "stop db")
+		return nil
+	})
+	app.OnStop(func(ctx context.Context) error {
+		calls = append(calls, "stop cache")
+		return nil
+	})
+	if err := app.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var expected = []string{"start db", "start cache", "stop cache", "stop db"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("hook order %v, expected %v", calls, expected)
+	}
+}
+
+func TestAppStartRollback(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{})
+	app, err := NewApp(AppConfig{HttpServer: server, ShutdownTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	app.OnStart(func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("start hook should not be bounded without start timeout")
+		}
+		calls = append(calls, "start db")
+		return nil
+	})
+	app.OnStop(func(ctx context.Context) error {
+		calls = append(calls, "stop db")
+		return nil
+	})
+	app.OnStart(func(ctx context.Context) error {
+		calls = append(calls, "start cache")
+		return errors.New("cache unavailable")
+	})
+	app.OnStop(func(ctx context.Context) error {
+		calls = append(calls, "stop cache")
+		return nil
+	})
+	if err := app.Run(); err == nil || err.Error() != "cache unavailable" {
+		t.Fatalf("run error %v", err)
+	}
+	var expected = []string{"start db", "start cache", "stop db"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("hook order %v, expected %v", calls, expected)
+	}
+}
+
+func TestAppConfigInvalid(t *testing.T) {
+	if _, err := NewApp(AppConfig{}); err == nil {
+		t.Error("app without server should return error")
+	}
+}
@@ -1,5 +1,7 @@
 package serving
 
+import "net/http"
+
 type StatusCode int
 type StatusMsg string
 
@@ -17,13 +19,21 @@ const (
 	STATUS_CODE_AUTH_CHECK_TOKEN_TIMEOUT   = 803
 	STATUS_CODE_AUTH_TOKEN_GENERATE_FAILED = 804
 	STATUS_CODE_AUTH_TYPE_ERROR                = 805
+	STATUS_CODE_AUTH_REFRESH_TOKEN_FAILED  = 806
+	STATUS_CODE_AUTH_REFRESH_TOKEN_REUSED  = 807
+	STATUS_CODE_AUTH_TOKEN_REVOKED         = 808
 	// secret
 	STATUS_CODE_SECRET_CHECK_FAILED    = 809
+	STATUS_CODE_SECRET_REPLAYED        = 814
+	STATUS_CODE_SECRET_APP_INVALID     = 815
+	// auth
+	STATUS_CODE_AUTH_FORBIDDEN         = 810
 
 	// upload
 	STATUS_CODE_UPLOAD_FILE_SAVE_FAILED        = 811
 	STATUS_CODE_UPLOAD_FILE_CHECK_FAILED       = 812
 	STATUS_CODE_UPLOAD_FILE_CHECK_FORMAT_WRONG = 813
+	STATUS_CODE_UPLOAD_FILE_TOO_LARGE          = 816
 )
 
 const (
@@ -40,13 +50,21 @@ const (
 	STATUS_MSG_AUTH_CHECK_TOKEN_TIMEOUT   = "用户信息已过期"
 	STATUS_MSG_AUTH_TOKEN_GENERATE_FAILED = "Token生成失败"
 	STATUS_MSG_AUTH_TYPE_ERROR            = "Token校验类型错误"
+	STATUS_MSG_AUTH_REFRESH_TOKEN_FAILED  = "登录已失效，请重新登录"
+	STATUS_MSG_AUTH_REFRESH_TOKEN_REUSED  = "登录凭证异常，请重新登录"
+	STATUS_MSG_AUTH_TOKEN_REVOKED         = "登录已注销，请重新登录"
 	// secret
 	STATUS_MSG_SECRET_CHECK_FAILED    = "安全校验失败"
+	STATUS_MSG_SECRET_REPLAYED        = "请求已失效，请重新发起"
+	STATUS_MSG_SECRET_APP_INVALID     = "应用未授权"
+	// auth
+	STATUS_MSG_AUTH_FORBIDDEN         = "无访问权限"
 
 	// upload
 	STATUS_MSG_UPLOAD_FILE_SAVE_FAILED        = "文件保存失败"
 	STATUS_MSG_UPLOAD_FILE_CHECK_FAILED       = "文件检查失败"
 	STATUS_MSG_UPLOAD_FILE_CHECK_FORMAT_WRONG = "文件校验错误，文件格式或大小不正确"
+	STATUS_MSG_UPLOAD_FILE_TOO_LARGE          = "文件大小超出限制"
 )
 
 var StatusCodeMsgMap = map[StatusCode]string {
@@ -62,14 +80,23 @@ var StatusCodeMsgMap = map[StatusCode]string {
 	STATUS_CODE_AUTH_CHECK_TOKEN_TIMEOUT   : STATUS_MSG_AUTH_CHECK_TOKEN_TIMEOUT,
 	STATUS_CODE_AUTH_TOKEN_GENERATE_FAILED : STATUS_MSG_AUTH_TOKEN_GENERATE_FAILED,
 	STATUS_CODE_AUTH_TYPE_ERROR            : STATUS_MSG_AUTH_TYPE_ERROR,
+	STATUS_CODE_AUTH_REFRESH_TOKEN_FAILED  : STATUS_MSG_AUTH_REFRESH_TOKEN_FAILED,
+	STATUS_CODE_AUTH_REFRESH_TOKEN_REUSED  : STATUS_MSG_AUTH_REFRESH_TOKEN_REUSED,
+	STATUS_CODE_AUTH_TOKEN_REVOKED         : STATUS_MSG_AUTH_TOKEN_REVOKED,
 
 	// secret
 	STATUS_CODE_SECRET_CHECK_FAILED:    STATUS_MSG_SECRET_CHECK_FAILED,
+	STATUS_CODE_SECRET_REPLAYED:        STATUS_MSG_SECRET_REPLAYED,
+	STATUS_CODE_SECRET_APP_INVALID:     STATUS_MSG_SECRET_APP_INVALID,
+
+	// auth
+	STATUS_CODE_AUTH_FORBIDDEN:         STATUS_MSG_AUTH_FORBIDDEN,
 
 	// upload
 	STATUS_CODE_UPLOAD_FILE_SAVE_FAILED:        STATUS_MSG_UPLOAD_FILE_SAVE_FAILED,
 	STATUS_CODE_UPLOAD_FILE_CHECK_FAILED:       STATUS_MSG_UPLOAD_FILE_CHECK_FAILED,
 	STATUS_CODE_UPLOAD_FILE_CHECK_FORMAT_WRONG: STATUS_MSG_UPLOAD_FILE_CHECK_FORMAT_WRONG,
+	STATUS_CODE_UPLOAD_FILE_TOO_LARGE:          STATUS_MSG_UPLOAD_FILE_TOO_LARGE,
 }
 
 func (code StatusCode) Msg() string {
@@ -78,4 +105,55 @@ func (code StatusCode) Msg() string {
 		return msg
 	}
 	return StatusCodeMsgMap[STATUS_CODE_ERROR]
-}
\ No newline at end of file
+}
+
+/**
+ * 状态码对应http状态，HttpStatusOpen开启后生效，未配置的状态码为500
+ */
+var StatusCodeHttpStatusMap = map[StatusCode]int {
+	// base
+	STATUS_CODE_SUCCESS:        http.StatusOK,
+	STATUS_CODE_INVALID_PARAMS: http.StatusBadRequest,
+	STATUS_CODE_ERROR:          http.StatusInternalServerError,
+	STATUS_CODE_FAILED:         http.StatusBadRequest,
+
+	// jwt token
+	STATUS_CODE_AUTH_CHECK_TOKEN_EMPTY     : http.StatusUnauthorized,
+	STATUS_CODE_AUTH_CHECK_TOKEN_FAILED    : http.StatusUnauthorized,
+	STATUS_CODE_AUTH_CHECK_TOKEN_TIMEOUT   : http.StatusUnauthorized,
+	STATUS_CODE_AUTH_TOKEN_GENERATE_FAILED : http.StatusInternalServerError,
+	STATUS_CODE_AUTH_TYPE_ERROR            : http.StatusUnauthorized,
+	STATUS_CODE_AUTH_REFRESH_TOKEN_FAILED  : http.StatusUnauthorized,
+	STATUS_CODE_AUTH_REFRESH_TOKEN_REUSED  : http.StatusUnauthorized,
+	STATUS_CODE_AUTH_TOKEN_REVOKED         : http.StatusUnauthorized,
+
+	// secret
+	STATUS_CODE_SECRET_CHECK_FAILED:    http.StatusBadRequest,
+	STATUS_CODE_SECRET_REPLAYED:        http.StatusBadRequest,
+	STATUS_CODE_SECRET_APP_INVALID:     http.StatusForbidden,
+
+	// auth
+	STATUS_CODE_AUTH_FORBIDDEN:         http.StatusForbidden,
+
+	// upload
+	STATUS_CODE_UPLOAD_FILE_SAVE_FAILED:        http.StatusInternalServerError,
+	STATUS_CODE_UPLOAD_FILE_CHECK_FAILED:       http.StatusInternalServerError,
+	STATUS_CODE_UPLOAD_FILE_CHECK_FORMAT_WRONG: http.StatusUnsupportedMediaType,
+	STATUS_CODE_UPLOAD_FILE_TOO_LARGE:          http.StatusRequestEntityTooLarge,
+}
+
+func (code StatusCode) HttpStatus() int {
+	status, ok := StatusCodeHttpStatusMap[code]
+	if ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+/**
+ * 注册应用自定义状态码，提示信息及对应http状态，需在服务启动前调用
+ */
+func StatusCodeRegister(code StatusCode, msg string, httpStatus int) {
+	StatusCodeMsgMap[code] = msg
+	StatusCodeHttpStatusMap[code] = httpStatus
+}
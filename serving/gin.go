@@ -6,14 +6,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/sean-tech/gokit/encrypt"
 	"github.com/sean-tech/gokit/foundation"
 	"github.com/sean-tech/gokit/validate"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/textproto"
 	"os"
-	"os/signal"
+	"sync"
 	"time"
 )
 
@@ -21,6 +24,7 @@ type secret_method string
 const (
 	key_request_id 					= "gohttp/key_request_id"
 	key_ctx_requestion              = "gohttp/key_ctx_requestion"
+	key_ctx_server              	= "gohttp/key_ctx_server"
 	_ secret_method 				= ""
 	secret_method_rsa               = "secret_method_rsa"
 	secret_method_aes               = "secret_method_aes"
@@ -32,7 +36,14 @@ type CError interface {
 	Msg() string
 }
 
+/** 携带响应数据的错误，ResponseError时data写入响应 **/
+type CDataError interface {
+	CError
+	Data() interface{}
+}
+
 type HttpConfig struct {
+	// gin运行模式为进程级设置，同一进程多个server时仅首个创建的server生效
 	RunMode 			string			`json:"run_mode" validate:"required,oneof=debug test release"`
 	WorkerId 			int64			`json:"worker_id" validate:"min=0"`
 	HttpPort            int				`json:"http_port" validate:"required,min=1,max=10000"`
@@ -42,38 +53,96 @@ type HttpConfig struct {
 	JwtSecret 			string			`json:"jwt_secret" validate:"required,gte=1"`
 	JwtIssuer 			string			`json:"jwt_issuer" validate:"required,gte=1"`
 	JwtExpiresTime 		time.Duration	`json:"jwt_expires_time" validate:"required,gte=1"`
+	JwtRefreshExpiresTime time.Duration	`json:"jwt_refresh_expires_time" validate:"gte=0"`
+	JwtMaxSessions 		int 			`json:"jwt_max_sessions" validate:"min=0"`
+	// jwt非对称签名密钥，配置后使用JwtSigningKid对应密钥签发，全部密钥均可校验
+	JwtKeys 			[]JwtKeyConfig	`json:"jwt_keys" validate:"dive"`
+	JwtSigningKid 		string			`json:"jwt_signing_kid"`
+	// 滑动过期，开启后剩余有效期不足JwtSlidingRenewBefore(为0时取有效期一半)时由响应头X-Renewed-Token下发新token
+	JwtSlidingOpen 		bool			`json:"jwt_sliding_open"`
+	JwtSlidingRenewBefore time.Duration	`json:"jwt_sliding_renew_before" validate:"gte=0"`
 	// storage
 	Logger       		IGinLogger    	`json:"logger" validate:"required"`
 	SecretStorage 		ISecretStorage  `json:"secret_storage" validate:"required"`
+	SecretKeyPrefix 	string 			`json:"secret_key_prefix"`
+	// rbac角色权限策略，RequirePermission使用，为nil时仅管理员可通过
+	RbacPolicy 			IRbacPolicy 	`json:"rbac_policy"`
 	// secret
 	SecretOpen			bool			`json:"secret_open"`
 	ServerPubKey 		string 			`json:"server_pub_key"`
 	ServerPriKey 		string 			`json:"server_pri_key"`
+	// 服务端密钥轮换，配置后替代ServerPubKey/ServerPriKey，以ServerKeyVersion(为空时取首个未退役)签名
+	ServerKeys 			[]ServerKeyPair	`json:"server_keys" validate:"dive"`
+	ServerKeyVersion 	string			`json:"server_key_version"`
 	ClientPubKey 		string 			`json:"client_pub_key"`
+	// 多应用接入，请求携带X-App-Id时使用注册表中对应应用的公钥及加密方式限制
+	AppRegistry 		IAppRegistry 	`json:"app_registry"`
+	// aes通道模式cbc/gcm/compatible，为空时为cbc，gcm版本经请求头X-Secret-Version: 2选择
+	SecretAesMode 		string			`json:"secret_aes_mode" validate:"omitempty,oneof=cbc gcm compatible"`
+	// 防重放，开启后加密参数须携带timestamp(unix秒)及nonce，时间偏差超出窗口或nonce重复时拒绝，窗口为0时默认5分钟
+	SecretReplayOpen 	bool			`json:"secret_replay_open"`
+	SecretReplayWindow 	time.Duration	`json:"secret_replay_window" validate:"gte=0"`
+	// 加密通道错误响应同样加密签名，通道建立前的拦截错误仍为明文
+	SecretErrorOpen 	bool			`json:"secret_error_open"`
+	// 响应格式，为空时为默认信封{code,msg,data,sign}，路由组可经UseResponder单独设置
+	Responder 			Responder		`json:"responder"`
+	// 响应http状态按状态码映射(StatusCodeHttpStatusMap)，关闭时恒为200
+	HttpStatusOpen 		bool			`json:"http_status_open"`
 }
+/** gin运行模式仅设置一次 **/
+var _ginModeOnce sync.Once
+
 /** 服务注册回调函数 **/
 type GinRegisterFunc func(engine *gin.Engine)
 
-var (
-	_httpConfig HttpConfig
-	_idWorker   foundation.SnowId
-)
+/**
+ * api server实例，配置、id生成器、engine均归属实例，同一进程可运行多个
+ */
+type HttpServer struct {
+	config 			HttpConfig
+	idWorker   		foundation.SnowId
+	engine 			*gin.Engine
+	server 			*http.Server
+	secretManager 	ISecretManager
+}
 
 /**
- * 启动 api server
- * handler: 接口实现serveHttp的对象
+ * 创建 api server，配置校验失败返回error
  */
-func HttpServerServe(config HttpConfig, registerFunc GinRegisterFunc) {
+func NewHttpServer(config HttpConfig) (*HttpServer, error) {
 	if err := validate.ValidateParameter(config); err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if err := checkServerKeys(config); err != nil {
+		return nil, err
+	}
+	idWorker, err := foundation.NewWorker(config.WorkerId)
+	if err != nil {
+		return nil, err
+	}
+	var jwtKeySet *JwtKeySet
+	if len(config.JwtKeys) > 0 {
+		if jwtKeySet, err = NewJwtKeySet(config.JwtKeys, config.JwtSigningKid); err != nil {
+			return nil, err
+		}
+	}
+	this := &HttpServer{
+		config:        config,
+		idWorker:      idWorker,
+		secretManager: NewSecretManager(SecretManagerConfig{
+			Storage:     config.SecretStorage,
+			KeyPrefix:   config.SecretKeyPrefix,
+			MaxSessions: config.JwtMaxSessions,
+			IdWorker:    idWorker,
+			JwtKeySet:   jwtKeySet,
+		}),
 	}
-	_httpConfig = config
-	_idWorker, _ = foundation.NewWorker(config.WorkerId)
 
 	// gin
-	gin.SetMode(config.RunMode)
-	gin.DisableConsoleColor()
-	gin.DefaultWriter = io.MultiWriter(config.Logger.Writer(), os.Stdout)
+	_ginModeOnce.Do(func() {
+		gin.SetMode(config.RunMode)
+		gin.DisableConsoleColor()
+	})
 
 	// engine
 	//engine := gin.Default()
@@ -81,52 +150,116 @@ func HttpServerServe(config HttpConfig, registerFunc GinRegisterFunc) {
 	engine.Use(gin.Recovery())
 	//engine.StaticFS(config.Upload.FileSavePath, http.Dir(GetUploadFilePath()))
 	engine.Use(func(ctx *gin.Context) {
+		ctx.Set(key_ctx_server, this)
 		newRequestion(ctx)
-		foundation.NewRequestion(ctx).RequestId = uint64(_idWorker.GetId())
+		foundation.NewRequestion(ctx).RequestId = uint64(this.idWorker.GetId())
 		ctx.Set(key_request_id, foundation.GetRequisition(ctx).RequestId)
 		ctx.Next()
 	})
-	engine.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		// 你的自定义格式
-		return fmt.Sprintf("[GIN] %s request_id:%d | %s | \"%s %s %s %d %s \"%s\" %s\"\n",
-			param.TimeStamp.Format("2006-01-02 15:04:05"),
-			param.Keys[key_request_id].(uint64),
-			param.ClientIP,
-			param.Method,
-			param.Path,
-			param.Request.Proto,
-			param.StatusCode,
-			param.Latency,
-			param.Request.UserAgent(),
-			param.ErrorMessage,
-		)
+	engine.Use(gin.LoggerWithConfig(gin.LoggerConfig{
+		Formatter: func(param gin.LogFormatterParams) string {
+			// 你的自定义格式
+			return fmt.Sprintf("[GIN] %s request_id:%d | %s | \"%s %s %s %d %s \"%s\" %s\"\n",
+				param.TimeStamp.Format("2006-01-02 15:04:05"),
+				param.Keys[key_request_id].(uint64),
+				param.ClientIP,
+				param.Method,
+				param.Path,
+				param.Request.Proto,
+				param.StatusCode,
+				param.Latency,
+				param.Request.UserAgent(),
+				param.ErrorMessage,
+			)
+		},
+		Output: io.MultiWriter(config.Logger.Writer(), os.Stdout),
 	}))
-	registerFunc(engine)
+	this.engine = engine
+
 	// server
-	s := http.Server{
+	this.server = &http.Server{
 		Addr:           fmt.Sprintf(":%d", config.HttpPort),
 		Handler:        engine,
 		ReadTimeout:    config.ReadTimeout,
 		WriteTimeout:   config.WriteTimeout,
 		MaxHeaderBytes: 1 << 20,
 	}
+	return this, nil
+}
+
+/**
+ * gin engine，用于注册路由及中间件
+ */
+func (this *HttpServer) Engine() *gin.Engine {
+	return this.engine
+}
+
+/**
+ * 配置信息
+ */
+func (this *HttpServer) Config() HttpConfig {
+	return this.config
+}
+
+/**
+ * 绑定当前server存储的secret manager
+ */
+func (this *HttpServer) SecretManager() ISecretManager {
+	return this.secretManager
+}
+
+/**
+ * 启动监听，端口监听失败返回error，监听成功后异步处理请求
+ */
+func (this *HttpServer) Start() error {
+	listener, err := net.Listen("tcp", this.server.Addr)
+	if err != nil {
+		return err
+	}
 	go func() {
-		if err := s.ListenAndServe(); err != nil {
-			log.Fatal(fmt.Sprintf("Listen: %v\n", err))
+		if err := this.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Listen: %v\n", err)
 		}
 	}()
-	// signal
-	quit := make(chan os.Signal)
-	signal.Notify(quit, os.Interrupt)
-	<- quit
-	log.Println("Shutdown Server ...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
-	defer cancel()
-	if err := s.Shutdown(ctx); err != nil {
+	return nil
+}
+
+/**
+ * 优雅关闭，等待处理中的请求完成或ctx超时
+ */
+func (this *HttpServer) Shutdown(ctx context.Context) error {
+	return this.server.Shutdown(ctx)
+}
+
+/**
+ * 启动 api server，阻塞直至收到中断信号
+ * registerFunc: 路由注册回调函数
+ */
+func HttpServerServe(config HttpConfig, registerFunc GinRegisterFunc) {
+	server, err := newGlobalHttpServer(config, registerFunc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	app, err := NewApp(AppConfig{HttpServer: server})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := app.Run(); err != nil {
 		log.Fatal("Server Shutdown:", err)
 	}
-	log.Println("Server exiting")
+}
+
+/**
+ * 创建全局api server，注册路由并设为GetSecretManager的默认manager
+ */
+func newGlobalHttpServer(config HttpConfig, registerFunc GinRegisterFunc) (*HttpServer, error) {
+	server, err := NewHttpServer(config)
+	if err != nil {
+		return nil, err
+	}
+	registerFunc(server.Engine())
+	setDefaultSecretManager(server.SecretManager())
+	return server, nil
 }
 
 type Gin struct {
@@ -138,6 +271,10 @@ type Gin struct {
  */
 type requisition struct {
 	SecretMethod secret_method `json:"secretMethod"`
+	SessionId    string        `json:"sessionId"`
+	SecretVersion string       `json:"secretVersion"`
+	App          *ClientApp    `json:"app"`
+	TokenInfo    *TokenInfo    `json:"tokenInfo"`
 	Params       []byte        `json:"params"`
 	Key          []byte        `json:"key"`
 }
@@ -167,25 +304,115 @@ func (g *Gin) getRequisition() *requisition {
 }
 
 /**
- * 参数绑定
+ * 信息获取，获取context绑定的server实例
+ */
+func (g *Gin) getServer() *HttpServer {
+	obj := g.Ctx.Value(key_ctx_server)
+	if server, ok := obj.(*HttpServer); ok {
+		return server
+	}
+	return nil
+}
+
+/**
+ * 获取token信息，含自定义claims，经InterceptToken校验后有效，否则返回nil
+ */
+func (g *Gin) TokenInfo() *TokenInfo {
+	if rq := g.getRequisition(); rq != nil {
+		return rq.TokenInfo
+	}
+	return nil
+}
+
+/**
+ * 获取token自定义claim
+ */
+func (g *Gin) TokenClaim(key string) (interface{}, bool) {
+	if tokenInfo := g.TokenInfo(); tokenInfo != nil {
+		return tokenInfo.Claim(key)
+	}
+	return nil, false
+}
+
+/**
+ * 参数绑定，加密请求绑定解密后的json参数，绑定后按binding及validate标签校验，失败返回字段级错误
  */
 func (g *Gin) BindParameter(parameter interface{}) error {
 
+	var err error
 	switch g.getRequisition().SecretMethod {
 	case secret_method_nouse:
-		if err := g.Ctx.Bind(parameter); err != nil {
-			return foundation.NewError(STATUS_CODE_INVALID_PARAMS, err.Error())
-		}
-		g.LogRequestParam(parameter)
+		err = g.Ctx.Bind(parameter)
+	case secret_method_aes, secret_method_rsa:
+		err = json.Unmarshal(g.getRequisition().Params, parameter)
+	default:
 		return nil
-	case secret_method_aes:
-	case secret_method_rsa:
-		if err := json.Unmarshal(g.getRequisition().Params, parameter); err != nil {
-			return foundation.NewError(STATUS_CODE_INVALID_PARAMS, err.Error())
+	}
+	if err == nil {
+		err = validateParameter(parameter)
+	}
+	if err != nil {
+		return parameterError(parameter, err)
+	}
+	g.LogRequestParam(parameter)
+	return nil
+}
+
+/**
+ * 组合参数绑定，请求体参数(加密请求为解密后的json)合并路径参数、query及请求头，分别对应uri、form、header标签
+ * 仅显式声明标签且请求中存在的字段被绑定，全部来源绑定后统一校验，加密通道下的路径变量亦可使用
+ * 明文请求路径参数、query及请求头覆盖请求体；加密请求解密后的json最后绑定，冲突时以加密参数为准，防止明文篡改
+ */
+func (g *Gin) BindCombinedParameter(parameter interface{}) error {
+
+	var err error
+	var form map[string][]string
+	var secretParams []byte
+	switch g.getRequisition().SecretMethod {
+	case secret_method_nouse:
+		if g.Ctx.ContentType() == binding.MIMEJSON {
+			if g.Ctx.Request.ContentLength != 0 {
+				err = json.NewDecoder(g.Ctx.Request.Body).Decode(parameter)
+			}
+			form = g.Ctx.Request.URL.Query()
+		} else {
+			if err = g.Ctx.Request.ParseMultipartForm(32 << 20); err == http.ErrNotMultipart {
+				err = nil
+			}
+			form = g.Ctx.Request.Form
 		}
-		g.LogRequestParam(parameter)
-		return nil
+	case secret_method_aes, secret_method_rsa:
+		secretParams = g.getRequisition().Params
+		form = g.Ctx.Request.URL.Query()
+	}
+	if err == nil {
+		err = bindTaggedValues(parameter, "uri", func(key string) []string {
+			if value, ok := g.Ctx.Params.Get(key); ok {
+				return []string{value}
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = bindTaggedValues(parameter, "form", func(key string) []string {
+			return form[key]
+		})
+	}
+	if err == nil {
+		err = bindTaggedValues(parameter, "header", func(key string) []string {
+			return g.Ctx.Request.Header[textproto.CanonicalMIMEHeaderKey(key)]
+		})
+	}
+	if err == nil && secretParams != nil {
+		err = json.Unmarshal(secretParams, parameter)
+	}
+	if err == nil {
+		err = validateParameter(parameter)
 	}
+	if err != nil {
+		return parameterError(parameter, err)
+	}
+	g.LogRequestParam(parameter)
 	return nil
 }
 
@@ -201,37 +428,89 @@ func (g *Gin) ResponseData(data interface{}) {
 		return
 	case secret_method_aes:
 		jsonBytes, _ := json.Marshal(data)
-		if secretBytes, err := encrypt.GetAes().EncryptCBC(jsonBytes, g.getRequisition().Key); err == nil {
-			g.LogResponseInfo(code, code.Msg(), jsonBytes, "")
-			g.Response(code, code.Msg(), base64.StdEncoding.EncodeToString(secretBytes), "")
-			return
+		if !g.responseSecret(code, code.Msg(), jsonBytes) {
+			g.Response(code, code.Msg(), data, "response data aes encrypt failed")
 		}
-		g.Response(code, code.Msg(), data, "response data aes encrypt failed")
 		return
 	case secret_method_rsa:
 		jsonBytes, _ := json.Marshal(data)
-		if secretBytes, err := encrypt.GetRsa().Encrypt(_httpConfig.ClientPubKey, jsonBytes); err == nil {
-			if signBytes, err := encrypt.GetRsa().Sign(_httpConfig.ServerPriKey, jsonBytes); err == nil {
-				sign := base64.StdEncoding.EncodeToString(signBytes)
-				g.LogResponseInfo(code, code.Msg(), jsonBytes, sign)
-				g.Response(code, code.Msg(), base64.StdEncoding.EncodeToString(secretBytes), sign)
-				return
-			}
+		if !g.responseSecret(code, code.Msg(), jsonBytes) {
+			g.Response(code, code.Msg(), data, "response data rsa encrypt failed")
 		}
-		g.Response(code, code.Msg(), data, "response data rsa encrypt failed")
 		return
 	}
 }
 
+/**
+ * 加密通道响应，aes加密，rsa加密并以当前服务端私钥签名，data为加密后的base64，失败返回false
+ */
+func (g *Gin) responseSecret(code StatusCode, msg string, jsonBytes []byte) bool {
+
+	switch g.getRequisition().SecretMethod {
+	case secret_method_aes:
+		secretBytes, err := secretAesEncrypt(g.getRequisition().SecretVersion, jsonBytes, g.getRequisition().Key)
+		if err != nil {
+			return false
+		}
+		g.Ctx.Header(HEADER_SECRET_VERSION, g.getRequisition().SecretVersion)
+		g.LogResponseInfo(code, msg, jsonBytes, "")
+		g.render(code, msg, base64.StdEncoding.EncodeToString(secretBytes), "")
+		return true
+	case secret_method_rsa:
+		config := g.getServer().config
+		secretBytes, err := secretRsaEncrypt(g.clientPubKey(&config), g.getRequisition().Key, jsonBytes)
+		if err != nil {
+			return false
+		}
+		serverKey := config.currentServerKey()
+		signBytes, err := encrypt.GetRsa().Sign(serverKey.PriKey, jsonBytes)
+		if err != nil {
+			return false
+		}
+		sign := base64.StdEncoding.EncodeToString(signBytes)
+		if serverKey.Version != "" {
+			g.Ctx.Header(HEADER_KEY_VERSION, serverKey.Version)
+		}
+		g.LogResponseInfo(code, msg, jsonBytes, sign)
+		g.render(code, msg, base64.StdEncoding.EncodeToString(secretBytes), sign)
+		return true
+	}
+	return false
+}
+
 /**
  * 响应数据，自定义error
  */
 func (g *Gin) ResponseError(err error) {
+	var code StatusCode = STATUS_CODE_FAILED
+	var msg = err.Error()
+	var data interface{}
 	if e, ok := err.(CError); ok {
-		g.Response(StatusCode(e.Code()), e.Msg(), nil, "")
+		code, msg = StatusCode(e.Code()), e.Msg()
+	}
+	if e, ok := err.(CDataError); ok {
+		data = e.Data()
+	}
+	// 加密通道错误响应，{code,msg,data}加密签名后写入data，明文msg仅为状态码通用提示
+	if g.getRequisition().SecretMethod != secret_method_nouse && g.getServer() != nil && g.getServer().config.SecretErrorOpen {
+		jsonBytes, _ := json.Marshal(SecretErrorBody{Code: code, Msg: msg, Data: data})
+		if !g.responseSecret(code, code.Msg(), jsonBytes) {
+			// 加密失败不降级为明文错误详情，记录后仅返回状态码通用提示
+			g.LogResponseInfo(code, msg, data, "error response encrypt failed")
+			g.render(code, code.Msg(), nil, "")
+		}
 		return
 	}
-	g.Response(STATUS_CODE_FAILED, err.Error(), nil, "")
+	g.Response(code, msg, data, "")
+}
+
+/**
+ * 加密错误响应体
+ */
+type SecretErrorBody struct {
+	Code 	StatusCode		`json:"code"`
+	Msg 	string			`json:"msg"`
+	Data 	interface{}		`json:"data"`
 }
 
 /**
@@ -242,13 +521,15 @@ func (g *Gin) Response(statusCode StatusCode, msg string, data interface{}, sign
 	if g.getRequisition().SecretMethod == secret_method_nouse || statusCode != STATUS_CODE_SUCCESS {
 		g.LogResponseInfo(statusCode, msg, data, sign)
 	}
-	g.Ctx.JSON(http.StatusOK, gin.H{
-		"code" : statusCode,
-		"msg" :  msg,
-		"data" : data,
-		"sign" : sign,
-	})
-	return
+	g.render(statusCode, msg, data, sign)
+}
+
+func (g *Gin) render(statusCode StatusCode, msg string, data interface{}, sign string) {
+	status := http.StatusOK
+	if server := g.getServer(); server != nil && server.config.HttpStatusOpen {
+		status = statusCode.HttpStatus()
+	}
+	g.responder().Render(g.Ctx, ResponseBody{Code: statusCode, Msg: msg, Data: data, Sign: sign, Status: status})
 }
 
 
@@ -258,24 +539,32 @@ type IGinLogger interface {
 }
 
 func (g *Gin) LogRequestParam(parameter interface{}) {
+	server := g.getServer()
+	if server == nil {
+		return
+	}
 	var requestion = foundation.GetRequisition(g.Ctx)
 	if jsonBytes, ok := parameter.([]byte); ok {
-		_httpConfig.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | params:", string(jsonBytes), "\n")
+		server.config.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | params:", string(jsonBytes), "\n")
 	} else if jsonBytes, err := json.Marshal(parameter); err == nil {
-		_httpConfig.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | params:", string(jsonBytes), "\n")
+		server.config.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | params:", string(jsonBytes), "\n")
 	} else {
-		_httpConfig.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | params:", parameter, "\n")
+		server.config.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | params:", parameter, "\n")
 	}
 }
 
 func (g *Gin) LogResponseInfo(statusCode StatusCode, msg string, data interface{}, sign string) {
+	server := g.getServer()
+	if server == nil {
+		return
+	}
 	var requestion = foundation.GetRequisition(g.Ctx)
 	if jsonBytes, ok := data.([]byte); ok {
-		_httpConfig.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | response code:", statusCode, " | msg:", msg, " | data:", string(jsonBytes), " | sign:", sign, "\n")
+		server.config.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | response code:", statusCode, " | msg:", msg, " | data:", string(jsonBytes), " | sign:", sign, "\n")
 	} else if jsonBytes, err := json.Marshal(data); err == nil {
-		_httpConfig.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | response code:", statusCode, " | msg:", msg, " | data:", string(jsonBytes), " | sign:", sign, "\n")
+		server.config.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | response code:", statusCode, " | msg:", msg, " | data:", string(jsonBytes), " | sign:", sign, "\n")
 	} else {
-		_httpConfig.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | response code:", statusCode, " | msg:", msg, " | data:", data, " | sign:", sign, "\n")
+		server.config.Logger.Gin("request_id:", requestion.RequestId, "user_name:", requestion.UserName, " | response code:", statusCode, " | msg:", msg, " | data:", data, " | sign:", sign, "\n")
 	}
 }
 
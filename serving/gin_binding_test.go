@@ -0,0 +1,115 @@
+package serving
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+)
+
+type bindingTestPage struct {
+	Page int `json:"page" form:"page" binding:"min=1"`
+}
+
+type bindingTestParameter struct {
+	bindingTestPage
+	GoodsId  int64    `json:"goodsId" uri:"goodsId" binding:"required"`
+	Tags     []string `json:"tags" form:"tag"`
+	ClientId string   `json:"clientId" header:"X-Client-Id"`
+	Amount   float64  `json:"amount" binding:"gt=0"`
+}
+
+func TestBindCombinedParameter(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{SecretOpen: true})
+	secretMgr := server.SecretManager()
+	handler := func(ctx *gin.Context) {
+		g := Gin{ctx}
+		var parameter bindingTestParameter
+		if err := g.BindCombinedParameter(&parameter); err != nil {
+			g.ResponseError(err)
+			return
+		}
+		g.ResponseData(parameter)
+	}
+	server.Engine().POST("/api/goods/v1/:goodsId/pay", handler)
+	server.Engine().POST("/api/goods/v2/:goodsId/pay", secretMgr.InterceptToken(), secretMgr.InterceptAes(), handler)
+
+	// 明文
+	req := httptest.NewRequest("POST", "/api/goods/v1/1001/pay?page=2&tag=a&tag=b&Amount=999", bytes.NewBufferString(`{"amount": 10.5}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-client-id", "ios")
+	recorder := httptest.NewRecorder()
+	server.Engine().ServeHTTP(recorder, req)
+	var resp struct {
+		Code float64
+		Data bindingTestParameter
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &resp)
+	if resp.Code != STATUS_CODE_SUCCESS {
+		t.Fatalf("response %s", recorder.Body.String())
+	}
+	if p := resp.Data; p.GoodsId != 1001 || p.Page != 2 || len(p.Tags) != 2 || p.ClientId != "ios" || p.Amount != 10.5 {
+		t.Errorf("combined parameter %+v", p)
+	}
+
+	// 加密，未声明标签的字段不可被query覆盖，加密参数优先于路径参数及query
+	config := server.Config()
+	token, _ := secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	tokenInfo, _ := secretMgr.ParseToken(token, config.JwtSecret, config.JwtIssuer)
+	key, _ := secretMgr.GetAesKey(tokenInfo.Id)
+	keyBytes, _ := hex.DecodeString(key)
+	_, body := serveTestRequest(server, "POST", "/api/goods/v2/1002/pay?Amount=0.01&page=3", map[string]string{"Authorization": token}, aesTestSecret(t, map[string]interface{}{"goodsId": 2002, "page": 4, "amount": 20}, keyBytes))
+	if body["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Fatalf("aes response %v", body)
+	}
+	var p bindingTestParameter
+	json.Unmarshal(aesTestDecrypt(t, body["data"], keyBytes), &p)
+	if p.GoodsId != 2002 || p.Page != 4 || p.Amount != 20 {
+		t.Errorf("aes combined parameter %+v", p)
+	}
+
+	// 校验失败
+	_, body = serveTestRequest(server, "POST", "/api/goods/v1/1001/pay?page=0", nil, map[string]interface{}{"amount": 1})
+	if body["code"].(float64) != STATUS_CODE_INVALID_PARAMS {
+		t.Errorf("invalid page code %v", body["code"])
+	}
+	_, body = serveTestRequest(server, "POST", "/api/goods/v1/abc/pay?page=1", nil, map[string]interface{}{"amount": 1})
+	if body["code"].(float64) != STATUS_CODE_INVALID_PARAMS {
+		t.Errorf("invalid goodsId code %v", body["code"])
+	}
+}
+
+func TestBindParameterAes(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{SecretOpen: true})
+	secretMgr := server.SecretManager()
+	server.Engine().POST("/api/goods/v1/pay", secretMgr.InterceptToken(), secretMgr.InterceptAes(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		var parameter bindingTestParameter
+		if err := g.BindParameter(&parameter); err != nil {
+			g.ResponseError(err)
+			return
+		}
+		g.ResponseData(parameter)
+	})
+	config := server.Config()
+	token, _ := secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	tokenInfo, _ := secretMgr.ParseToken(token, config.JwtSecret, config.JwtIssuer)
+	key, _ := secretMgr.GetAesKey(tokenInfo.Id)
+	keyBytes, _ := hex.DecodeString(key)
+
+	_, body := serveTestRequest(server, "POST", "/api/goods/v1/pay", map[string]string{"Authorization": token}, aesTestSecret(t, map[string]interface{}{"goodsId": 1001, "page": 1, "amount": 5}, keyBytes))
+	if body["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Fatalf("aes response %v", body)
+	}
+	var p bindingTestParameter
+	json.Unmarshal(aesTestDecrypt(t, body["data"], keyBytes), &p)
+	if p.GoodsId != 1001 || p.Amount != 5 {
+		t.Errorf("aes parameter %+v", p)
+	}
+	_, body = serveTestRequest(server, "POST", "/api/goods/v1/pay", map[string]string{"Authorization": token}, aesTestSecret(t, map[string]interface{}{"page": 1, "amount": 5}, keyBytes))
+	if body["code"].(float64) != STATUS_CODE_INVALID_PARAMS {
+		t.Errorf("missing goodsId code %v", body["code"])
+	}
+}
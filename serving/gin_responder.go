@@ -0,0 +1,136 @@
+package serving
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+const (
+	key_ctx_responder 		= "gohttp/key_ctx_responder"
+	// 非信封格式响应的签名头
+	HEADER_RESPONSE_SIGN 	= "sign"
+	MIME_PROBLEM_JSON 		= "application/problem+json"
+)
+
+/**
+ * 响应内容，data为原数据或加密后的base64，sign为rsa通道签名
+ */
+type ResponseBody struct {
+	Code 	StatusCode		`json:"code"`
+	Msg 	string			`json:"msg"`
+	Data 	interface{}		`json:"data"`
+	Sign 	string			`json:"sign"`
+	// http状态，HttpStatusOpen开启时为状态码映射值，否则为200
+	Status 	int				`json:"-"`
+}
+
+/** 响应输出接口，决定响应格式及http状态 **/
+type Responder interface {
+	Render(ctx *gin.Context, body ResponseBody)
+}
+
+/**
+ * 函数适配Responder
+ */
+type ResponderFunc func(ctx *gin.Context, body ResponseBody)
+
+func (f ResponderFunc) Render(ctx *gin.Context, body ResponseBody) {
+	f(ctx, body)
+}
+
+/**
+ * 默认信封格式{code,msg,data,sign}
+ */
+type EnvelopeResponder struct {}
+
+func (EnvelopeResponder) Render(ctx *gin.Context, body ResponseBody) {
+	ctx.JSON(body.Status, gin.H{
+		"code" : body.Code,
+		"msg" :  body.Msg,
+		"data" : body.Data,
+		"sign" : body.Sign,
+	})
+}
+
+/**
+ * RFC 7807 problem+json，成功时直接输出data，签名写入响应头sign，错误时输出problem，http状态恒为状态码映射值
+ */
+type ProblemResponder struct {
+	// problem type前缀，为空时type为about:blank，否则为前缀+状态码，如 https://api.example.com/problems/400
+	TypeBase string
+}
+
+/**
+ * problem详情，code为业务状态码，errors为错误附带数据(如参数字段错误)
+ */
+type Problem struct {
+	Type 		string			`json:"type"`
+	Title 		string			`json:"title"`
+	Status 		int				`json:"status"`
+	Detail 		string			`json:"detail,omitempty"`
+	Instance 	string			`json:"instance,omitempty"`
+	Code 		StatusCode		`json:"code"`
+	Errors 		interface{}		`json:"errors,omitempty"`
+}
+
+func (this ProblemResponder) Render(ctx *gin.Context, body ResponseBody) {
+	if body.Sign != "" {
+		ctx.Header(HEADER_RESPONSE_SIGN, body.Sign)
+	}
+	if body.Code == STATUS_CODE_SUCCESS {
+		ctx.JSON(http.StatusOK, body.Data)
+		return
+	}
+	status := body.Code.HttpStatus()
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    body.Code.Msg(),
+		Status:   status,
+		Detail:   body.Msg,
+		Instance: ctx.Request.URL.Path,
+		Code:     body.Code,
+		Errors:   body.Data,
+	}
+	if this.TypeBase != "" {
+		problem.Type = this.TypeBase + strconv.Itoa(status)
+	}
+	ctx.Render(status, problemRender{problem})
+}
+
+type problemRender struct {
+	problem Problem
+}
+
+func (this problemRender) Render(w http.ResponseWriter) error {
+	this.WriteContentType(w)
+	return json.NewEncoder(w).Encode(this.problem)
+}
+
+func (this problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header()["Content-Type"] = []string{MIME_PROBLEM_JSON}
+}
+
+/**
+ * 路由组响应格式，优先于server配置，如 group.Use(UseResponder(ProblemResponder{}))
+ */
+func UseResponder(responder Responder) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(key_ctx_responder, responder)
+		ctx.Next()
+	}
+}
+
+/**
+ * 当前请求的响应格式，路由组 > server配置 > 默认信封
+ */
+func (g *Gin) responder() Responder {
+	if responder, ok := g.Ctx.Value(key_ctx_responder).(Responder); ok {
+		return responder
+	}
+	if server := g.getServer(); server != nil && server.config.Responder != nil {
+		return server.config.Responder
+	}
+	return EnvelopeResponder{}
+}
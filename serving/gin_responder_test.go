@@ -0,0 +1,174 @@
+package serving
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/sean-tech/gokit/foundation"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponder(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{})
+	handler := func(ctx *gin.Context) {
+		g := Gin{ctx}
+		if ctx.Query("fail") != "" {
+			g.ResponseError(&ParameterError{Fields: []ParameterFieldError{{Field: "name", Rule: "required", Message: "name 不能为空"}}})
+			return
+		}
+		g.ResponseData(map[string]string{"name": "sean"})
+	}
+	server.Engine().GET("/api/user/v1/info", handler)
+	rest := server.Engine().Group("/rest/v1", UseResponder(ProblemResponder{TypeBase: "https://sean.tech/problems/"}))
+	rest.GET("/info", handler)
+	rest.GET("/token", server.SecretManager().InterceptToken(), handler)
+	hook := server.Engine().Group("/hook/v1", UseResponder(ResponderFunc(func(ctx *gin.Context, body ResponseBody) {
+		ctx.String(http.StatusOK, body.Msg)
+	})))
+	hook.GET("/info", handler)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.Engine().ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		return recorder
+	}
+
+	// 默认信封
+	if recorder := serve("/api/user/v1/info?fail=1"); recorder.Code != http.StatusOK {
+		t.Errorf("envelope status %d", recorder.Code)
+	} else {
+		var body ResponseBody
+		json.Unmarshal(recorder.Body.Bytes(), &body)
+		if body.Code != STATUS_CODE_INVALID_PARAMS || body.Data == nil {
+			t.Errorf("envelope body %s", recorder.Body.String())
+		}
+	}
+
+	// problem+json
+	recorder := serve("/rest/v1/info")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"name":"sean"}` {
+		t.Errorf("problem responder success %d %s", recorder.Code, recorder.Body.String())
+	}
+	recorder = serve("/rest/v1/info?fail=1")
+	var problem Problem
+	json.Unmarshal(recorder.Body.Bytes(), &problem)
+	if recorder.Code != http.StatusBadRequest || recorder.Header().Get("Content-Type") != MIME_PROBLEM_JSON {
+		t.Errorf("problem status %d, content type %s", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if problem.Type != "https://sean.tech/problems/400" || problem.Code != STATUS_CODE_INVALID_PARAMS || problem.Instance != "/rest/v1/info" || problem.Errors == nil {
+		t.Errorf("problem %+v", problem)
+	}
+	// 拦截器错误同样使用路由组格式
+	recorder = serve("/rest/v1/token")
+	json.Unmarshal(recorder.Body.Bytes(), &problem)
+	if recorder.Code != http.StatusUnauthorized || problem.Code != STATUS_CODE_AUTH_CHECK_TOKEN_EMPTY {
+		t.Errorf("intercept problem %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	// 自定义
+	if recorder := serve("/hook/v1/info?fail=1"); recorder.Body.String() != "name 不能为空" {
+		t.Errorf("custom responder %s", recorder.Body.String())
+	}
+}
+
+func TestServerResponder(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{Responder: ProblemResponder{}})
+	server.Engine().GET("/api/user/v1/info", func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseError(foundation.NewError(STATUS_CODE_ERROR, "db down"))
+	})
+	recorder := httptest.NewRecorder()
+	server.Engine().ServeHTTP(recorder, httptest.NewRequest("GET", "/api/user/v1/info", nil))
+	var problem Problem
+	json.Unmarshal(recorder.Body.Bytes(), &problem)
+	if recorder.Code != http.StatusInternalServerError || problem.Type != "about:blank" || problem.Detail != "db down" || problem.Title != STATUS_MSG_ERROR {
+		t.Errorf("server problem %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestHttpStatusMapping(t *testing.T) {
+	const STATUS_CODE_ORDER_NOT_FOUND StatusCode = 10404
+	StatusCodeRegister(STATUS_CODE_ORDER_NOT_FOUND, "订单不存在", http.StatusNotFound)
+	defer func() {
+		delete(StatusCodeMsgMap, STATUS_CODE_ORDER_NOT_FOUND)
+		delete(StatusCodeHttpStatusMap, STATUS_CODE_ORDER_NOT_FOUND)
+	}()
+	server := newSecretTestServer(t, HttpConfig{HttpStatusOpen: true})
+	server.Engine().GET("/api/order/v1/info", server.SecretManager().InterceptToken(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseData(nil)
+	})
+	server.Engine().GET("/api/order/v1/error", func(ctx *gin.Context) {
+		g := Gin{ctx}
+		code := STATUS_CODE_ORDER_NOT_FOUND
+		switch ctx.Query("code") {
+		case "800":
+			code = STATUS_CODE_FAILED
+		case "812":
+			code = STATUS_CODE_UPLOAD_FILE_CHECK_FAILED
+		case "413":
+			code = STATUS_CODE_UPLOAD_FILE_TOO_LARGE
+		case "415":
+			code = STATUS_CODE_UPLOAD_FILE_CHECK_FORMAT_WRONG
+		case "500":
+			code = STATUS_CODE_ERROR
+		case "999":
+			code = 999
+		}
+		g.ResponseError(foundation.NewError(int(code), code.Msg()))
+	})
+	config := server.Config()
+	token, _ := server.SecretManager().GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+
+	var cases = []struct {
+		path   string
+		token  string
+		status int
+	}{
+		{"/api/order/v1/info", token, http.StatusOK},
+		{"/api/order/v1/info", "", http.StatusUnauthorized},
+		{"/api/order/v1/info", "invalid", http.StatusUnauthorized},
+		{"/api/order/v1/error", "", http.StatusNotFound},
+		{"/api/order/v1/error?code=800", "", http.StatusBadRequest},
+		{"/api/order/v1/error?code=812", "", http.StatusInternalServerError},
+		{"/api/order/v1/error?code=413", "", http.StatusRequestEntityTooLarge},
+		{"/api/order/v1/error?code=415", "", http.StatusUnsupportedMediaType},
+		{"/api/order/v1/error?code=500", "", http.StatusInternalServerError},
+		{"/api/order/v1/error?code=999", "", http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		status, resp := serveTestRequest(server, "GET", c.path, map[string]string{"Authorization": c.token}, nil)
+		if status != c.status || resp["code"] == nil {
+			t.Errorf("%s status %d, expected %d, body %v", c.path, status, c.status, resp)
+		}
+	}
+
+	// 未开启时恒为200
+	server = newSecretTestServer(t, HttpConfig{})
+	server.Engine().GET("/api/order/v1/info", server.SecretManager().InterceptToken(), func(ctx *gin.Context) {})
+	if status, _ := serveTestRequest(server, "GET", "/api/order/v1/info", nil, nil); status != http.StatusOK {
+		t.Errorf("status %d, expected 200 when mapping closed", status)
+	}
+}
+
+func TestParseTokenError(t *testing.T) {
+	var secret = "ahsjdadusba"
+	var issuer = "sean.test"
+	secretMgr := NewSecretManager(SecretManagerConfig{})
+	expired, err := secretMgr.GenerateToken(1230090123, "seantest1", false, secret, issuer, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases = map[string]int{
+		expired:   STATUS_CODE_AUTH_CHECK_TOKEN_TIMEOUT,
+		"invalid": STATUS_CODE_AUTH_CHECK_TOKEN_FAILED,
+	}
+	for token, code := range cases {
+		_, err := secretMgr.ParseToken(token, secret, issuer)
+		if e, ok := err.(CError); !ok || e.Code() != code {
+			t.Errorf("parse token %s error %v, expected code %d", token, err, code)
+		}
+	}
+}
@@ -3,14 +3,19 @@ package serving
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/sean-tech/gokit/encrypt"
 	"github.com/sean-tech/gokit/foundation"
 	"github.com/sean-tech/gokit/logging"
 	"github.com/sean-tech/gokit/validate"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 )
@@ -35,12 +40,9 @@ type GoodsPayParameter struct {
 }
 
 func TestGinServer(t *testing.T) {
-	logging.Setup(logging.LogConfig{
-		LogSavePath:     "/Users/lyra/Desktop/",
-		LogPrefix:       "gintest",
-	})
+	setupTestLogging()
 	// server start
-	HttpServerServe(HttpConfig{
+	server, err := NewHttpServer(HttpConfig{
 		RunMode:        "debug",
 		WorkerId:       0,
 		HttpPort:       8001,
@@ -55,7 +57,27 @@ func TestGinServer(t *testing.T) {
 		ClientPubKey:   "",
 		Logger:         logging.Logger(),
 		SecretStorage:  NewMemeoryStorage(),
-	}, RegisterApi)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterApi(server.Engine())
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	TestPostToGinServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHttpServerConfigInvalid(t *testing.T) {
+	if _, err := NewHttpServer(HttpConfig{RunMode: "debug"}); err == nil {
+		t.Error("invalid config should return error")
+	}
 }
 
 func RegisterApi(engine *gin.Engine) {
@@ -137,4 +159,181 @@ func TestPostToGinServer(t *testing.T)  {
 		fmt.Println(statuscode)
 		fmt.Println(hea)
 	}
-}
\ No newline at end of file
+}
+
+var _testLoggingOnce sync.Once
+
+/**
+ * 测试日志，全部测试共用一次初始化，避免重复Setup产生数据竞争
+ */
+func setupTestLogging() {
+	_testLoggingOnce.Do(func() {
+		logging.Setup(logging.LogConfig{
+			LogSavePath:     "/Users/lyra/Desktop/",
+			LogPrefix:       "servingtest",
+		})
+	})
+}
+
+/**
+ * 测试用server，secret开启，内存存储
+ */
+func newSecretTestServer(t *testing.T, config HttpConfig) *HttpServer {
+	server, err := NewHttpServer(newSecretTestConfig(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+/**
+ * 测试用server配置
+ */
+func newSecretTestConfig(config HttpConfig) HttpConfig {
+	setupTestLogging()
+	config.RunMode = "test"
+	config.HttpPort = 8003
+	config.ReadTimeout = 60 * time.Second
+	config.WriteTimeout = 60 * time.Second
+	config.JwtSecret = "webkit/serving/jwtsecret/token@20200427"
+	config.JwtIssuer = "sean.tech/webkit/user"
+	config.JwtExpiresTime = time.Hour
+	config.Logger = logging.Logger()
+	if config.SecretStorage == nil {
+		config.SecretStorage = NewMemeoryStorage()
+	}
+	return config
+}
+
+/**
+ * 测试请求，返回响应体
+ */
+func serveTestRequest(server *HttpServer, method, path string, header map[string]string, body interface{}) (int, map[string]interface{}) {
+	jsonBytes, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(jsonBytes))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	recorder := httptest.NewRecorder()
+	server.Engine().ServeHTTP(recorder, req)
+	var resp map[string]interface{}
+	json.Unmarshal(recorder.Body.Bytes(), &resp)
+	return recorder.Code, resp
+}
+
+func TestTokenCustomClaims(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{})
+	secretMgr := server.SecretManager()
+	server.Engine().POST("/api/user/v1/claims", secretMgr.InterceptToken(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		tenant, _ := g.TokenClaim("tenant")
+		g.ResponseData(map[string]interface{}{
+			"admin":  g.TokenInfo().IsAdministrotor,
+			"tenant": tenant,
+			"roles":  g.TokenInfo().Claims["roles"],
+		})
+	})
+
+	config := server.Config()
+	claims := map[string]interface{}{"tenant": "sean", "roles": []string{"editor"}}
+	pair, err := secretMgr.GenerateTokenPairWithClaims(1230090123, "seantest1", true, claims, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime, 2 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 刷新后claims保持不变
+	refreshed, err := secretMgr.RefreshToken(pair.RefreshToken, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime, 2 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{pair.AccessToken, refreshed.AccessToken} {
+		_, resp := serveTestRequest(server, "POST", "/api/user/v1/claims", map[string]string{"Authorization": token}, nil)
+		if resp["code"].(float64) != STATUS_CODE_SUCCESS {
+			t.Fatalf("code %v, expected success", resp["code"])
+		}
+		data := resp["data"].(map[string]interface{})
+		if data["admin"] != true || data["tenant"] != "sean" {
+			t.Errorf("claims %v", data)
+		}
+		if roles, ok := data["roles"].([]interface{}); !ok || len(roles) != 1 || roles[0] != "editor" {
+			t.Errorf("roles %v", data["roles"])
+		}
+	}
+}
+
+func TestSecretErrorResponse(t *testing.T) {
+	serverPubKey, serverPriKey := rsaTestKeyPair(t)
+	clientPubKey, clientPriKey := rsaTestKeyPair(t)
+	server := newSecretTestServer(t, HttpConfig{
+		SecretOpen:      true,
+		SecretErrorOpen: true,
+		ServerPubKey:    serverPubKey,
+		ServerPriKey:    serverPriKey,
+		ClientPubKey:    clientPubKey,
+	})
+	secretMgr := server.SecretManager()
+	failed := func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseError(foundation.NewError(STATUS_CODE_FAILED, "余额不足"))
+	}
+	server.Engine().POST("/api/user/v1/pay", secretMgr.InterceptToken(), secretMgr.InterceptAes(), failed)
+	server.Engine().POST("/api/user/v1/rsapay", secretMgr.InterceptRsa(), failed)
+	server.Engine().POST("/api/user/v1/brokenpay", secretMgr.InterceptToken(), secretMgr.InterceptAes(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.getRequisition().Key = []byte("broken")
+		g.ResponseError(&ParameterError{Fields: []ParameterFieldError{{Field: "cardNo", Rule: "len", Message: "cardNo 6222021234"}}})
+	})
+
+	// aes
+	config := server.Config()
+	token, _ := secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	tokenInfo, _ := secretMgr.ParseToken(token, config.JwtSecret, config.JwtIssuer)
+	key, _ := secretMgr.GetAesKey(tokenInfo.Id)
+	keyBytes, _ := hex.DecodeString(key)
+	_, resp := serveTestRequest(server, "POST", "/api/user/v1/pay", map[string]string{"Authorization": token}, aesTestSecret(t, map[string]string{"hello": "world"}, keyBytes))
+	if resp["code"].(float64) != STATUS_CODE_FAILED || resp["msg"] != STATUS_MSG_FAILED {
+		t.Fatalf("aes error response %v", resp)
+	}
+	var body SecretErrorBody
+	if err := json.Unmarshal(aesTestDecrypt(t, resp["data"], keyBytes), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != STATUS_CODE_FAILED || body.Msg != "余额不足" {
+		t.Errorf("aes error body %+v", body)
+	}
+
+	// rsa，可验签
+	jsonBytes := []byte(`{"hello":"world"}`)
+	signBytes, _ := encrypt.GetRsa().Sign(clientPriKey, jsonBytes)
+	secret, _ := encrypt.GetRsa().Encrypt(serverPubKey, jsonBytes)
+	_, resp = serveTestRequest(server, "POST", "/api/user/v1/rsapay", map[string]string{"sign": base64.StdEncoding.EncodeToString(signBytes)}, map[string]string{
+		"secret": base64.StdEncoding.EncodeToString(secret),
+	})
+	if resp["code"].(float64) != STATUS_CODE_FAILED {
+		t.Fatalf("rsa error response %v", resp)
+	}
+	encrypted, _ := base64.StdEncoding.DecodeString(resp["data"].(string))
+	decrypted, err := encrypt.GetRsa().Decrypt(clientPriKey, encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	respSign, _ := base64.StdEncoding.DecodeString(resp["sign"].(string))
+	if err := encrypt.GetRsa().Verify(serverPubKey, decrypted, respSign); err != nil {
+		t.Errorf("rsa error response sign verify failed, %v", err)
+	}
+	if err := json.Unmarshal(decrypted, &body); err != nil || body.Msg != "余额不足" {
+		t.Errorf("rsa error body %s, %v", decrypted, err)
+	}
+
+	// 加密失败不回落明文详情
+	_, resp = serveTestRequest(server, "POST", "/api/user/v1/brokenpay", map[string]string{"Authorization": token}, aesTestSecret(t, map[string]string{"hello": "world"}, keyBytes))
+	if resp["code"].(float64) != STATUS_CODE_INVALID_PARAMS || resp["msg"] != STATUS_MSG_INVALID_PARAMS || resp["data"] != nil {
+		t.Errorf("encrypt failed error response %v", resp)
+	}
+
+	// 通道建立前的错误仍为明文
+	_, resp = serveTestRequest(server, "POST", "/api/user/v1/pay", map[string]string{"Authorization": token}, map[string]string{"secret": "invalid"})
+	if resp["code"].(float64) != STATUS_CODE_INVALID_PARAMS || resp["msg"] != STATUS_MSG_INVALID_PARAMS || resp["sign"] != "" {
+		t.Errorf("intercept error response %v", resp)
+	}
+}
@@ -0,0 +1,204 @@
+package serving
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/sean-tech/gokit/foundation"
+	_ "github.com/sean-tech/gokit/validate"
+	"log"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	_ "unsafe"
+)
+
+/**
+ * 参数字段校验错误，field为json字段路径，rule为校验规则
+ */
+type ParameterFieldError struct {
+	Field 		string	`json:"field"`
+	Rule 		string	`json:"rule"`
+	Param 		string	`json:"param,omitempty"`
+	Message 	string	`json:"message"`
+}
+
+/**
+ * 参数校验错误，响应时字段错误列表写入data
+ */
+type ParameterError struct {
+	Fields []ParameterFieldError
+}
+
+func (this *ParameterError) Error() string {
+	return this.Msg()
+}
+
+func (this *ParameterError) Code() int {
+	return STATUS_CODE_INVALID_PARAMS
+}
+
+func (this *ParameterError) Msg() string {
+	if len(this.Fields) == 0 {
+		return STATUS_MSG_INVALID_PARAMS
+	}
+	return this.Fields[0].Message
+}
+
+func (this *ParameterError) Data() interface{} {
+	return this.Fields
+}
+
+/**
+ * 校验规则提示，%s为规则参数
+ */
+var ValidationRuleMsgMap = map[string]string{
+	"required" 	: "不能为空",
+	"len" 		: "长度须为%s",
+	"min" 		: "不能小于%s",
+	"max" 		: "不能大于%s",
+	"gte" 		: "不能小于%s",
+	"lte" 		: "不能大于%s",
+	"gt" 		: "须大于%s",
+	"lt" 		: "须小于%s",
+	"eq" 		: "须等于%s",
+	"ne" 		: "不能等于%s",
+	"oneof" 	: "须为[%s]之一",
+	"email" 	: "邮箱格式不正确",
+	"base64" 	: "须为base64编码",
+}
+
+/**
+ * gokit validate注册的正则标签(tag -> pattern)，含gokit内置及经validate.ValidationTagRegexpPatternRegister注册的标签
+ * gokit未提供读取接口，经linkname引用其注册表，校验规则与gokit保持一致
+ */
+//go:linkname gokitTagPatternMap github.com/sean-tech/gokit/validate._tagPatternMap
+var gokitTagPatternMap sync.Map
+
+/**
+ * binding标签校验，同gin Bind
+ */
+func validateBinding(parameter interface{}) error {
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(parameter)
+}
+
+/**
+ * 参数校验，binding标签及validate标签(支持gokit正则标签)，失败返回全部字段错误，非结构体参数(如map)不校验
+ */
+func validateParameter(parameter interface{}) error {
+	if err := validateBinding(parameter); err != nil {
+		return parameterError(parameter, err)
+	}
+	value := reflect.ValueOf(parameter)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	return parameterError(parameter, validateTags(parameter))
+}
+
+/**
+ * validate标签校验，同gokit每次校验时注册当前全部正则标签，返回全部字段错误
+ */
+func validateTags(parameter interface{}) error {
+	tagValidator := validator.New()
+	gokitTagPatternMap.Range(func(tag, pattern interface{}) bool {
+		tagRegexp, err := regexp.Compile(pattern.(string))
+		if err != nil {
+			log.Printf("validate tag %s pattern invalid: %v", tag, err)
+		}
+		tagValidator.RegisterValidation(tag.(string), func(fl validator.FieldLevel) bool {
+			return tagRegexp != nil && tagRegexp.MatchString(fl.Field().String())
+		})
+		return true
+	})
+	return tagValidator.Struct(parameter)
+}
+
+/**
+ * 绑定错误转换，binding标签校验错误转为字段级错误
+ */
+func parameterError(parameter interface{}, err error) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case validator.ValidationErrors:
+		var fields = make([]ParameterFieldError, 0, len(e))
+		for _, fe := range e {
+			fields = append(fields, newParameterFieldError(parameter, fe.StructNamespace(), fe.Tag(), fe.Param(), fe.Kind()))
+		}
+		return &ParameterError{Fields: fields}
+	case CError:
+		return err
+	}
+	return foundation.NewError(STATUS_CODE_INVALID_PARAMS, err.Error())
+}
+
+func newParameterFieldError(parameter interface{}, namespace, rule, param string, kind reflect.Kind) ParameterFieldError {
+	field := jsonFieldPath(reflect.TypeOf(parameter), namespace)
+	msg, ok := ValidationRuleMsgMap[rule]
+	if !ok {
+		msg = "格式不正确"
+	} else if strings.Contains(msg, "%s") {
+		if kind == reflect.String || kind == reflect.Slice || kind == reflect.Map || kind == reflect.Array {
+			switch rule {
+			case "min", "max", "gte", "lte", "gt", "lt":
+				msg = "长度" + msg
+			}
+		}
+		msg = fmt.Sprintf(msg, param)
+	}
+	return ParameterFieldError{
+		Field:   field,
+		Rule:    rule,
+		Param:   param,
+		Message: field + " " + msg,
+	}
+}
+
+/**
+ * 结构体命名空间转json字段路径，如 Parameter.Goods[0].GoodsId -> goods[0].goodsId，无json标签的匿名嵌入字段不计入
+ */
+func jsonFieldPath(t reflect.Type, namespace string) string {
+	segments := strings.Split(namespace, ".")
+	if len(segments) > 0 {
+		segments = segments[1:]
+	}
+	var path []string
+	for _, segment := range segments {
+		name, index := segment, ""
+		if i := strings.Index(segment, "["); i >= 0 {
+			name, index = segment[:i], segment[i:]
+		}
+		for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map) {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			path = append(path, segment)
+			t = nil
+			continue
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			path = append(path, segment)
+			t = nil
+			continue
+		}
+		t = field.Type
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.Anonymous && jsonName == "" {
+			continue
+		}
+		if jsonName == "" || jsonName == "-" {
+			jsonName = field.Name
+		}
+		path = append(path, jsonName+index)
+	}
+	return strings.Join(path, ".")
+}
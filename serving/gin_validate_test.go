@@ -0,0 +1,87 @@
+package serving
+
+import (
+	"github.com/gin-gonic/gin"
+	"reflect"
+	"testing"
+)
+
+type validateTestAddress struct {
+	Phone string `json:"phone" validate:"required,phone"`
+}
+
+type validateTestParameter struct {
+	UserName  string                `json:"userName" validate:"required,gte=2"`
+	Age       int                   `json:"age" validate:"min=1,max=150"`
+	Addresses []validateTestAddress `json:"addresses" validate:"dive"`
+}
+
+func TestBindParameterValidate(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{})
+	server.Engine().POST("/api/user/v1/add", func(ctx *gin.Context) {
+		g := Gin{ctx}
+		var parameter validateTestParameter
+		if err := g.BindParameter(&parameter); err != nil {
+			g.ResponseError(err)
+			return
+		}
+		g.ResponseData(parameter)
+	})
+
+	_, resp := serveTestRequest(server, "POST", "/api/user/v1/add", nil, map[string]interface{}{
+		"userName": "sean", "age": 18, "addresses": []map[string]string{{"phone": "13800138000"}},
+	})
+	if resp["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Fatalf("valid parameter response %v", resp)
+	}
+
+	// 多个字段不合法时全部返回
+	_, resp = serveTestRequest(server, "POST", "/api/user/v1/add", nil, map[string]interface{}{
+		"userName": "s", "age": 200, "addresses": []map[string]string{{"phone": "13800138000"}, {"phone": "123"}},
+	})
+	if resp["code"].(float64) != STATUS_CODE_INVALID_PARAMS {
+		t.Fatalf("invalid parameter code %v", resp["code"])
+	}
+	if got := validateTestFields(resp); !reflect.DeepEqual(got, [][2]string{{"userName", "gte"}, {"age", "max"}, {"addresses[1].phone", "phone"}}) {
+		t.Errorf("field errors %v", got)
+	}
+	if resp["msg"] != "userName 长度不能小于2" {
+		t.Errorf("msg %v", resp["msg"])
+	}
+
+	_, resp = serveTestRequest(server, "POST", "/api/user/v1/add", nil, map[string]interface{}{
+		"userName": "sean", "age": 18, "addresses": []map[string]string{{"phone": "13800138000"}, {"phone": "123"}},
+	})
+	if got := validateTestFields(resp); !reflect.DeepEqual(got, [][2]string{{"addresses[1].phone", "phone"}}) {
+		t.Errorf("nested field errors %v", got)
+	}
+
+	_, resp = serveTestRequest(server, "POST", "/api/user/v1/add", nil, map[string]interface{}{
+		"userName": "sean", "age": 200,
+	})
+	if resp["msg"] != "age 不能大于150" {
+		t.Errorf("msg %v", resp["msg"])
+	}
+}
+
+func validateTestFields(resp map[string]interface{}) [][2]string {
+	fields, _ := resp["data"].([]interface{})
+	var got [][2]string
+	for _, field := range fields {
+		field := field.(map[string]interface{})
+		got = append(got, [2]string{field["field"].(string), field["rule"].(string)})
+	}
+	return got
+}
+
+func TestParameterBindingErrors(t *testing.T) {
+	parameter := &bindingTestParameter{Amount: 1}
+	err := parameterError(parameter, validateBinding(parameter))
+	e, ok := err.(*ParameterError)
+	if !ok || len(e.Fields) != 2 {
+		t.Fatalf("binding error %v", err)
+	}
+	if e.Fields[0].Field != "page" || e.Fields[1].Field != "goodsId" || e.Fields[1].Rule != "required" {
+		t.Errorf("binding field errors %+v", e.Fields)
+	}
+}
@@ -0,0 +1,237 @@
+package serving
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/sean-tech/gokit/foundation"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+const (
+	// 角色所在的token自定义claim
+	TOKEN_CLAIM_ROLES = "roles"
+	// 全部权限
+	RBAC_PERMISSION_ALL = "*"
+)
+
+/** 角色权限策略接口 **/
+type IRbacPolicy interface {
+	Permissions(role string) ([]string, error)
+}
+
+/**
+ * 内存角色权限策略，角色 -> 权限列表
+ */
+func NewMemoryRbacPolicy(roles map[string][]string) *RbacMemoryPolicy {
+	this := &RbacMemoryPolicy{roles: make(map[string][]string)}
+	for role, permissions := range roles {
+		this.SetRole(role, permissions...)
+	}
+	return this
+}
+
+type RbacMemoryPolicy struct {
+	lock 	sync.RWMutex
+	roles 	map[string][]string
+}
+
+func (this *RbacMemoryPolicy) Permissions(role string) ([]string, error) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.roles[role], nil
+}
+
+/**
+ * 设置角色权限，覆盖原有权限
+ */
+func (this *RbacMemoryPolicy) SetRole(role string, permissions ...string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.roles[role] = append([]string(nil), permissions...)
+}
+
+/**
+ * 删除角色
+ */
+func (this *RbacMemoryPolicy) DeleteRole(role string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(this.roles, role)
+}
+
+/**
+ * 文件角色权限策略，json格式{"role": ["permission"]}，Reload重新加载
+ */
+func NewFileRbacPolicy(path string) (*RbacFilePolicy, error) {
+	this := &RbacFilePolicy{
+		RbacMemoryPolicy: NewMemoryRbacPolicy(nil),
+		path:             path,
+	}
+	if err := this.Reload(); err != nil {
+		return nil, err
+	}
+	return this, nil
+}
+
+type RbacFilePolicy struct {
+	*RbacMemoryPolicy
+	path string
+}
+
+func (this *RbacFilePolicy) Reload() error {
+	data, err := ioutil.ReadFile(this.path)
+	if err != nil {
+		return err
+	}
+	var roles map[string][]string
+	if err := json.Unmarshal(data, &roles); err != nil {
+		return err
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.roles = make(map[string][]string, len(roles))
+	for role, permissions := range roles {
+		this.roles[role] = permissions
+	}
+	return nil
+}
+
+/**
+ * 权限匹配，支持全部权限"*"及前缀通配"user:*"
+ */
+func rbacPermissionMatched(granted string, permission string) bool {
+	if granted == RBAC_PERMISSION_ALL || granted == permission {
+		return true
+	}
+	if strings.HasSuffix(granted, RBAC_PERMISSION_ALL) {
+		return strings.HasPrefix(permission, strings.TrimSuffix(granted, RBAC_PERMISSION_ALL))
+	}
+	return false
+}
+
+/**
+ * 获取token角色，取自自定义claim "roles"
+ */
+func (g *Gin) TokenRoles() []string {
+	value, ok := g.TokenClaim(TOKEN_CLAIM_ROLES)
+	if !ok {
+		return nil
+	}
+	switch roles := value.(type) {
+	case []string:
+		return roles
+	case []interface{}:
+		var result = make([]string, 0, len(roles))
+		for _, role := range roles {
+			if role, ok := role.(string); ok {
+				result = append(result, role)
+			}
+		}
+		return result
+	case string:
+		return []string{roles}
+	}
+	return nil
+}
+
+/**
+ * 是否拥有角色，管理员拥有全部角色
+ */
+func (g *Gin) HasRole(role string) bool {
+	if tokenInfo := g.TokenInfo(); tokenInfo != nil && tokenInfo.IsAdministrotor {
+		return true
+	}
+	for _, r := range g.TokenRoles() {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * 是否拥有权限，由token角色经server配置的策略展开，管理员拥有全部权限
+ */
+func (g *Gin) HasPermission(permission string) (bool, error) {
+	if tokenInfo := g.TokenInfo(); tokenInfo != nil && tokenInfo.IsAdministrotor {
+		return true, nil
+	}
+	server := g.getServer()
+	if server == nil || server.config.RbacPolicy == nil {
+		return false, nil
+	}
+	for _, role := range g.TokenRoles() {
+		permissions, err := server.config.RbacPolicy.Permissions(role)
+		if err != nil {
+			return false, err
+		}
+		for _, granted := range permissions {
+			if rbacPermissionMatched(granted, permission) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+/**
+ * 角色拦截，拥有任一角色即通过，需在InterceptToken之后
+ */
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		g := Gin{ctx}
+		if !rbacTokenChecked(&g) {
+			return
+		}
+		for _, role := range roles {
+			if g.HasRole(role) {
+				ctx.Next()
+				return
+			}
+		}
+		g.ResponseError(foundation.NewError(STATUS_CODE_AUTH_FORBIDDEN, STATUS_MSG_AUTH_FORBIDDEN))
+		ctx.Abort()
+	}
+}
+
+/**
+ * 权限拦截，须拥有全部权限方可通过，需在InterceptToken之后
+ */
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		g := Gin{ctx}
+		if !rbacTokenChecked(&g) {
+			return
+		}
+		for _, permission := range permissions {
+			ok, err := g.HasPermission(permission)
+			if err != nil {
+				// 策略错误不返回客户端，避免泄露存储等内部信息
+				g.LogResponseInfo(STATUS_CODE_ERROR, err.Error(), nil, "rbac policy failed")
+				g.ResponseError(foundation.NewError(STATUS_CODE_ERROR, STATUS_MSG_ERROR))
+				ctx.Abort()
+				return
+			}
+			if !ok {
+				g.ResponseError(foundation.NewError(STATUS_CODE_AUTH_FORBIDDEN, STATUS_MSG_AUTH_FORBIDDEN))
+				ctx.Abort()
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
+
+/**
+ * 未经InterceptToken校验时响应未登录并中止
+ */
+func rbacTokenChecked(g *Gin) bool {
+	if g.TokenInfo() == nil {
+		g.ResponseError(foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_EMPTY, STATUS_MSG_AUTH_CHECK_TOKEN_EMPTY))
+		g.Ctx.Abort()
+		return false
+	}
+	return true
+}
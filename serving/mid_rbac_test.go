@@ -0,0 +1,112 @@
+package serving
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRbacPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rbac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rbac.json")
+	ioutil.WriteFile(path, []byte(`{"editor": ["article:*"], "viewer": ["article:read"]}`), 0600)
+
+	policy, err := NewFileRbacPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if permissions, _ := policy.Permissions("viewer"); len(permissions) != 1 || permissions[0] != "article:read" {
+		t.Errorf("viewer permissions %v", permissions)
+	}
+	ioutil.WriteFile(path, []byte(`{"viewer": []}`), 0600)
+	if err := policy.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if permissions, _ := policy.Permissions("viewer"); len(permissions) != 0 {
+		t.Errorf("reloaded viewer permissions %v", permissions)
+	}
+	if _, err := NewFileRbacPolicy(filepath.Join(dir, "not_exist.json")); err == nil {
+		t.Error("not exist policy file should return error")
+	}
+
+	if !rbacPermissionMatched("article:*", "article:delete") || rbacPermissionMatched("article:*", "user:read") || !rbacPermissionMatched("*", "user:read") {
+		t.Error("permission wildcard match wrong")
+	}
+}
+
+func TestRequireRoleAndPermission(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{
+		RbacPolicy: NewMemoryRbacPolicy(map[string][]string{
+			"editor": {"article:*"},
+			"viewer": {"article:read"},
+		}),
+	})
+	secretMgr := server.SecretManager()
+	ok := func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseData(nil)
+	}
+	server.Engine().POST("/api/article/v1/read", secretMgr.InterceptToken(), RequirePermission("article:read"), ok)
+	server.Engine().POST("/api/article/v1/delete", secretMgr.InterceptToken(), RequirePermission("article:read", "article:delete"), ok)
+	server.Engine().POST("/api/article/v1/audit", secretMgr.InterceptToken(), RequireRole("auditor", "editor"), ok)
+	server.Engine().POST("/api/article/v1/nologin", RequireRole("editor"), ok)
+
+	config := server.Config()
+	newToken := func(isAdministrotor bool, roles ...string) string {
+		token, err := secretMgr.GenerateTokenWithClaims(1230090123, "seantest1", isAdministrotor, map[string]interface{}{TOKEN_CLAIM_ROLES: roles}, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	viewer, editor, admin := newToken(false, "viewer"), newToken(false, "editor"), newToken(true)
+
+	var cases = []struct {
+		path  string
+		token string
+		code  float64
+	}{
+		{"/api/article/v1/read", viewer, STATUS_CODE_SUCCESS},
+		{"/api/article/v1/delete", viewer, STATUS_CODE_AUTH_FORBIDDEN},
+		{"/api/article/v1/delete", editor, STATUS_CODE_SUCCESS},
+		{"/api/article/v1/audit", viewer, STATUS_CODE_AUTH_FORBIDDEN},
+		{"/api/article/v1/audit", editor, STATUS_CODE_SUCCESS},
+		{"/api/article/v1/delete", admin, STATUS_CODE_SUCCESS},
+		{"/api/article/v1/nologin", editor, STATUS_CODE_AUTH_CHECK_TOKEN_EMPTY},
+	}
+	for _, c := range cases {
+		_, resp := serveTestRequest(server, "POST", c.path, map[string]string{"Authorization": c.token}, nil)
+		if resp["code"].(float64) != c.code {
+			t.Errorf("%s code %v, expected %v", c.path, resp["code"], c.code)
+		}
+	}
+}
+
+/** 查询失败的角色策略 **/
+type rbacFailedPolicy struct {}
+
+func (this *rbacFailedPolicy) Permissions(role string) ([]string, error) {
+	return nil, errors.New("dial tcp 10.0.0.8:3306: connection refused")
+}
+
+func TestRequirePermissionPolicyFailed(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{RbacPolicy: &rbacFailedPolicy{}})
+	secretMgr := server.SecretManager()
+	server.Engine().POST("/api/article/v1/read", secretMgr.InterceptToken(), RequirePermission("article:read"), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseData(nil)
+	})
+	config := server.Config()
+	token, _ := secretMgr.GenerateTokenWithClaims(1230090123, "seantest1", false, map[string]interface{}{TOKEN_CLAIM_ROLES: []string{"viewer"}}, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	_, resp := serveTestRequest(server, "POST", "/api/article/v1/read", map[string]string{"Authorization": token}, nil)
+	if resp["code"].(float64) != STATUS_CODE_ERROR || resp["msg"] != STATUS_MSG_ERROR {
+		t.Errorf("policy failed response %v", resp)
+	}
+}
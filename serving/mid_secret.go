@@ -9,6 +9,8 @@ import (
 	"github.com/sean-tech/gokit/encrypt"
 	"github.com/sean-tech/gokit/foundation"
 	"github.com/sean-tech/gokit/validate"
+	"net/http"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -16,77 +18,191 @@ import (
 type TokenInfo struct {
 	UserId uint64 			`json:"userId"`
 	UserName string 		`json:"userName"`
+	IsAdministrotor bool 	`json:"isAdministrotor,omitempty"`
+	// 应用自定义claims，如角色、租户、设备、scope，解析后数字类型为float64
+	Claims map[string]interface{} 	`json:"claims,omitempty"`
 	jwt.StandardClaims
 }
 
+/**
+ * 获取自定义claim
+ */
+func (this *TokenInfo) Claim(key string) (interface{}, bool) {
+	if this.Claims == nil {
+		return nil, false
+	}
+	value, ok := this.Claims[key]
+	return value, ok
+}
 
-/** 存储接口 **/
+
+/** 存储接口，Get未命中须返回*SecretNotExistError，其余error视为存储故障 **/
 type ISecretStorage interface {
 	Set(key string, value interface{}, expiration time.Duration) error
 	Get(key string) (string, error)
 	Delete(key string)
 }
 
-func getSecretStorage() ISecretStorage {
-	if _httpConfig.SecretStorage != nil {
-		return _httpConfig.SecretStorage
-	}
-	return NewMemeoryStorage()
+/** 存储值不存在或已过期 **/
+type SecretNotExistError struct {
+	Key string
+}
+
+func (this *SecretNotExistError) Error() string {
+	return "value for key " + this.Key + " not exist"
+}
+
+/**
+ * 判断存储error是否为值不存在
+ */
+func IsSecretNotExist(err error) bool {
+	var notExist *SecretNotExistError
+	return errors.As(err, &notExist)
+}
+
+/** 存储原子写入接口，可选实现，防重放nonce记录、会话列表锁使用 **/
+type ISecretStorageSetNX interface {
+	SetNX(key string, value interface{}, expiration time.Duration) (bool, error)
 }
 
 type ISecretManager interface {
 	GenerateToken(userId uint64, userName string, isAdministrotor bool, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration) (string, error)
+	GenerateTokenWithClaims(userId uint64, userName string, isAdministrotor bool, claims map[string]interface{}, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration) (string, error)
 	ParseToken(token string, JwtSecret string, JwtIssuer string) (*TokenInfo, error)
 	CheckToken(token string, JwtSecret string, JwtIssuer string) error
-	GetAesKey(userName string) (key string, err error)
+	RenewToken(token string, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration) (string, error)
+	GetAesKey(sessionId string) (key string, err error)
+	ExchangeAesKey(sessionId string, clientPublicKey []byte, expiration time.Duration) (serverPublicKey []byte, err error)
+	HandshakeHandler() gin.HandlerFunc
+	ServerPubKeyHandler() gin.HandlerFunc
+	GenerateTokenPair(userId uint64, userName string, isAdministrotor bool, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration, RefreshExpiresTime time.Duration) (*TokenPair, error)
+	GenerateTokenPairWithClaims(userId uint64, userName string, isAdministrotor bool, claims map[string]interface{}, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration, RefreshExpiresTime time.Duration) (*TokenPair, error)
+	RefreshToken(refreshToken string, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration, RefreshExpiresTime time.Duration) (*TokenPair, error)
+	ListSessions(userName string) ([]*Session, error)
+	RevokeSession(userName string, sessionId string) error
+	RevokeUser(userName string) error
+	RevokeToken(token string, JwtSecret string, JwtIssuer string) error
+	JwksHandler() gin.HandlerFunc
 	InterceptToken() gin.HandlerFunc
 	InterceptRsa() gin.HandlerFunc
 	InterceptAes() gin.HandlerFunc
 }
 
-var (
-	_secretManagerOnce sync.Once
-	_secretManager *secretManagerImpl
-)
+type SecretManagerConfig struct {
+	// token、aes key、会话等存储，为nil时使用内存存储
+	Storage 		ISecretStorage
+	// 存储键前缀，区分应用
+	KeyPrefix 		string
+	// 每个用户最大会话数，超出时注销最早的会话，为0时不限制
+	MaxSessions 	int
+	// 会话id生成器，为nil时使用worker id 0
+	IdWorker 		foundation.SnowId
+	// jwt非对称密钥集，为nil或无签名密钥时使用JwtSecret以HS256签名
+	JwtKeySet 		*JwtKeySet
+}
 
-func GetSecretManager() ISecretManager {
-	_secretManagerOnce.Do(func() {
-		_secretManager = &secretManagerImpl{}
-	})
-	return _secretManager
+/**
+ * 创建secret manager，token及aes key按会话存储于storage的独立键空间
+ */
+func NewSecretManager(config SecretManagerConfig) ISecretManager {
+	if config.Storage == nil {
+		config.Storage = NewMemeoryStorage()
+	}
+	if config.IdWorker == nil {
+		config.IdWorker, _ = foundation.NewWorker(0)
+	}
+	return &secretManagerImpl{
+		keyspace:    NewSecretKeyspace(config.Storage, config.KeyPrefix),
+		maxSessions: config.MaxSessions,
+		idWorker:    config.IdWorker,
+		jwtKeySet:   config.JwtKeySet,
+	}
 }
 
 type secretManagerImpl struct {
+	keyspace 		*SecretKeyspace
+	maxSessions 	int
+	idWorker 		foundation.SnowId
+	jwtKeySet 		*JwtKeySet
+	// 会话列表本实例内锁，多实例间经存储加锁，见lockSessions
+	sessionLock 	sync.Mutex
+	// token续期锁，避免并发续期互相覆盖
+	renewLock 		sync.Mutex
 }
 
 /**
- * 生成token
+ * 生成token，每次调用创建新会话，会话id记录于jwt的jti
  */
 func (this *secretManagerImpl) GenerateToken(userId uint64, userName string, isAdministrotor bool, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration) (string, error) {
+	return this.GenerateTokenWithClaims(userId, userName, isAdministrotor, nil, JwtSecret, JwtIssuer, JwtExpiresTime)
+}
+
+/**
+ * 生成token，携带应用自定义claims，InterceptToken后可由Gin.TokenInfo()读取
+ */
+func (this *secretManagerImpl) GenerateTokenWithClaims(userId uint64, userName string, isAdministrotor bool, claims map[string]interface{}, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration) (string, error) {
+	_, token, err := this.createSession(TokenInfo{
+		UserId:          userId,
+		UserName:        userName,
+		IsAdministrotor: isAdministrotor,
+		Claims:          claims,
+	}, JwtSecret, JwtIssuer, JwtExpiresTime)
+	return token, err
+}
+
+/**
+ * 创建会话，签发token并生成会话aes key
+ */
+func (this *secretManagerImpl) createSession(info TokenInfo, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration) (sessionId string, token string, err error) {
+	sessionId = strconv.FormatInt(this.idWorker.GetId(), 10)
+	if err := this.addSession(&Session{
+		Id:        sessionId,
+		UserId:    info.UserId,
+		UserName:  info.UserName,
+		CreatedAt: time.Now().Unix(),
+		ExpiresAt: time.Now().Add(JwtExpiresTime).Unix(),
+	}); err != nil {
+		return "", "", foundation.NewError(STATUS_CODE_AUTH_TOKEN_GENERATE_FAILED, STATUS_MSG_AUTH_TOKEN_GENERATE_FAILED)
+	}
+	// 签发失败时移除会话，避免占用最大会话数
+	if token, err = this.signToken(sessionId, info, JwtSecret, JwtIssuer, JwtExpiresTime); err != nil {
+		this.removeSession(info.UserName, sessionId)
+		return "", "", err
+	}
+	if err := this.keyspace.Set(SECRET_SPACE_AESKEY, sessionId, hex.EncodeToString(encrypt.GetAes().GenerateKey()), JwtExpiresTime); err != nil {
+		this.removeSession(info.UserName, sessionId)
+		this.deleteSessionData(sessionId)
+		return "", "", foundation.NewError(STATUS_CODE_AUTH_TOKEN_GENERATE_FAILED, STATUS_MSG_AUTH_TOKEN_GENERATE_FAILED)
+	}
+	return sessionId, token, nil
+}
+
+/**
+ * 签发jwt并存储，info提供用户信息及自定义claims，标准claims在此填充
+ */
+func (this *secretManagerImpl) signToken(sessionId string, info TokenInfo, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration) (string, error) {
 	expireTime := time.Now().Add(JwtExpiresTime)
 	iat := time.Now().Unix()
-	//jti := _httpConfig.IdWorker.GetId()
-	c := TokenInfo{
-		UserId:			userId,
-		UserName:       userName,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expireTime.Unix(),
-			Issuer:    JwtIssuer,
-			//Id:strconv.FormatInt(jti, 10),
-			IssuedAt:iat,
-			NotBefore: iat,
-			Subject:"client",
-		},
+	c := info
+	c.StandardClaims = jwt.StandardClaims{
+		ExpiresAt: expireTime.Unix(),
+		Issuer:    JwtIssuer,
+		Id:        sessionId,
+		IssuedAt:iat,
+		NotBefore: iat,
+		Subject:"client",
 	}
-	tokenClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
-	token, err := tokenClaims.SignedString([]byte(JwtSecret))
-	if err != nil {
-		return "", foundation.NewError(STATUS_CODE_AUTH_TOKEN_GENERATE_FAILED, STATUS_MSG_AUTH_TOKEN_GENERATE_FAILED)
+	var token string
+	var err error
+	if this.jwtKeySet != nil && this.jwtKeySet.CanSign() {
+		token, err = this.jwtKeySet.Sign(c)
+	} else {
+		token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(JwtSecret))
 	}
-	if err := getSecretStorage().Set(userName, token, JwtExpiresTime); err != nil {
+	if err != nil {
 		return "", foundation.NewError(STATUS_CODE_AUTH_TOKEN_GENERATE_FAILED, STATUS_MSG_AUTH_TOKEN_GENERATE_FAILED)
 	}
-	if err := getSecretStorage().Set(userName, hex.EncodeToString(encrypt.GetAes().GenerateKey()), JwtExpiresTime); err != nil {
+	if err := this.keyspace.Set(SECRET_SPACE_TOKEN, sessionId, token, JwtExpiresTime); err != nil {
 		return "", foundation.NewError(STATUS_CODE_AUTH_TOKEN_GENERATE_FAILED, STATUS_MSG_AUTH_TOKEN_GENERATE_FAILED)
 	}
 	return token, nil
@@ -100,10 +216,19 @@ func (this *secretManagerImpl) ParseToken(token string, JwtSecret string, JwtIss
 		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_EMPTY, STATUS_MSG_AUTH_CHECK_TOKEN_EMPTY)
 	}
 	tokenClaims, err := jwt.ParseWithClaims(token, &TokenInfo{}, func(token *jwt.Token) (interface{}, error) {
+		// 带kid为非对称签名，否则为HS256
+		if _, ok := token.Header["kid"]; ok && this.jwtKeySet != nil {
+			return this.jwtKeySet.KeyFunc(token)
+		}
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("jwt signing method " + token.Method.Alg() + " not support")
+		}
 		return []byte(JwtSecret), nil
 	})
-	if err != nil {
-		return nil, err
+	if ve, ok := err.(*jwt.ValidationError); ok && ve.Errors&jwt.ValidationErrorExpired != 0 {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_TIMEOUT, STATUS_MSG_AUTH_CHECK_TOKEN_TIMEOUT)
+	} else if err != nil {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
 	}
 	if tokenClaims == nil {
 		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
@@ -115,12 +240,21 @@ func (this *secretManagerImpl) ParseToken(token string, JwtSecret string, JwtIss
 	if !ok {
 		return nil, foundation.NewError(STATUS_CODE_AUTH_TYPE_ERROR, STATUS_MSG_AUTH_TYPE_ERROR)
 	}
-	savedToken, err := getSecretStorage().Get(claims.UserName)
+	if claims.Id == "" {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
+	}
+	savedToken, err := this.keyspace.Get(SECRET_SPACE_TOKEN, claims.Id)
 	if err != nil {
+		if _, err := this.keyspace.Get(SECRET_SPACE_REVOKED, claims.Id); err == nil {
+			return nil, foundation.NewError(STATUS_CODE_AUTH_TOKEN_REVOKED, STATUS_MSG_AUTH_TOKEN_REVOKED)
+		}
 		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
 	}
 	if savedToken != token {
-		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
+		// 续期宽限期内的旧token
+		if previousToken, err := this.keyspace.Get(SECRET_SPACE_TOKEN_PREVIOUS, claims.Id); err != nil || previousToken != token {
+			return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
+		}
 	}
 	if claims.Issuer != JwtIssuer {
 		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
@@ -138,8 +272,25 @@ func (this *secretManagerImpl) CheckToken(token string, JwtSecret string, JwtIss
 	return nil
 }
 
-func (this *secretManagerImpl) GetAesKey(userName string) (key string, err error) {
-	return getSecretStorage().Get(userName)
+/**
+ * 获取会话aes key，会话id即token的jti
+ */
+func (this *secretManagerImpl) GetAesKey(sessionId string) (key string, err error) {
+	return this.keyspace.Get(SECRET_SPACE_AESKEY, sessionId)
+}
+
+/**
+ * jwks公钥文档，供其他服务校验token，未配置非对称密钥时keys为空
+ */
+func (this *secretManagerImpl) JwksHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		jwks := &Jwks{Keys: []*Jwk{}}
+		if this.jwtKeySet != nil {
+			jwks = this.jwtKeySet.Jwks()
+		}
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, jwks)
+	}
 }
 
 /**
@@ -148,8 +299,12 @@ func (this *secretManagerImpl) GetAesKey(userName string) (key string, err error
 func (this *secretManagerImpl) InterceptToken() gin.HandlerFunc {
 	handler := func(ctx *gin.Context) {
 		g := Gin{ctx}
+		config, ok := this.interceptConfig(&g)
+		if !ok {
+			return
+		}
 		// parse & check
-		tokenInfo, err := this.ParseToken(ctx.GetHeader("Authorization"), _httpConfig.JwtSecret, _httpConfig.JwtIssuer)
+		tokenInfo, err := this.ParseToken(ctx.GetHeader("Authorization"), config.JwtSecret, config.JwtIssuer)
 		if err != nil {
 			g.ResponseError(err)
 			ctx.Abort()
@@ -157,6 +312,14 @@ func (this *secretManagerImpl) InterceptToken() gin.HandlerFunc {
 		}
 		foundation.GetRequisition(ctx).UserId = tokenInfo.UserId
 		foundation.GetRequisition(ctx).UserName = tokenInfo.UserName
+		g.getRequisition().SessionId = tokenInfo.Id
+		g.getRequisition().TokenInfo = tokenInfo
+		// sliding renew
+		if config.JwtSlidingOpen && tokenNeedRenew(tokenInfo, config.JwtExpiresTime, config.JwtSlidingRenewBefore) {
+			if renewedToken, err := this.RenewToken(ctx.GetHeader("Authorization"), config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime); err == nil {
+				ctx.Header(HEADER_RENEWED_TOKEN, renewedToken)
+			}
+		}
 		// next
 		ctx.Next()
 	}
@@ -165,28 +328,59 @@ func (this *secretManagerImpl) InterceptToken() gin.HandlerFunc {
 
 
 
+/**
+ * 拦截器配置获取，取自context绑定的server实例，未绑定server时响应错误并中止
+ */
+func (this *secretManagerImpl) interceptConfig(g *Gin) (*HttpConfig, bool) {
+	server := g.getServer()
+	if server == nil {
+		var code StatusCode = STATUS_CODE_ERROR
+		g.Response(code, code.Msg(), nil, "")
+		g.Ctx.Abort()
+		return nil, false
+	}
+	return &server.config, true
+}
+
 type SecretParams struct {
 	Secret string	`json:"secret" validate:"required,base64"`
-} 
+	// rsa通道混合加密时为rsa加密的aes key
+	Key string 		`json:"key" validate:"omitempty,base64"`
+}
 
 /**
  * rsa拦截校验
  */
 func (this *secretManagerImpl) InterceptRsa() gin.HandlerFunc {
 	handler := func(ctx *gin.Context) {
-		if _httpConfig.SecretOpen == false {
+		g := Gin{ctx}
+		config, ok := this.interceptConfig(&g)
+		if !ok {
+			return
+		}
+		if config.SecretOpen == false {
 			ctx.Next()
 			return
 		}
 
-		g := Gin{ctx}
 		var code StatusCode = STATUS_CODE_SUCCESS
 		var params SecretParams
 		var encrypted []byte
 		var jsonBytes []byte
+		var aesKey []byte
 		var sign = ctx.GetHeader("sign")
 		var signDatas, _ = base64.StdEncoding.DecodeString(sign)
 
+		// app handle
+		if app, err := resolveClientApp(&g, config, APP_SECRET_METHOD_RSA); err != nil {
+			code = STATUS_CODE_SECRET_APP_INVALID
+			g.Response(code, code.Msg(),nil, "")
+			ctx.Abort()
+			return
+		} else {
+			g.getRequisition().App = app
+		}
+
 		// params handle
 		if err := g.Ctx.Bind(&params); err != nil { // bind
 			code = STATUS_CODE_SECRET_CHECK_FAILED
@@ -194,10 +388,12 @@ func (this *secretManagerImpl) InterceptRsa() gin.HandlerFunc {
 			code = STATUS_CODE_INVALID_PARAMS
 		} else if encrypted, err = base64.StdEncoding.DecodeString(params.Secret); err != nil { // decode
 			code = STATUS_CODE_SECRET_CHECK_FAILED
-		} else if jsonBytes, err = encrypt.GetRsa().Decrypt(_httpConfig.ServerPriKey, encrypted); err != nil { // decrypt
+		} else if jsonBytes, aesKey, err = secretRsaDecryptWithKeys(config, ctx.GetHeader(HEADER_KEY_VERSION), params, encrypted); err != nil { // decrypt
 			code = STATUS_CODE_SECRET_CHECK_FAILED
-		} else if err = encrypt.GetRsa().Verify(_httpConfig.ClientPubKey, jsonBytes, signDatas); err != nil { // sign verify
+		} else if err = encrypt.GetRsa().Verify(g.clientPubKey(config), jsonBytes, signDatas); err != nil { // sign verify
 			code = STATUS_CODE_SECRET_CHECK_FAILED
+		} else {
+			code = this.checkReplay(config, jsonBytes) // replay check
 		}
 		// code check
 		if code != STATUS_CODE_SUCCESS {
@@ -207,6 +403,7 @@ func (this *secretManagerImpl) InterceptRsa() gin.HandlerFunc {
 		}
 		g.getRequisition().SecretMethod = secret_method_rsa
 		g.getRequisition().Params = jsonBytes
+		g.getRequisition().Key = aesKey
 		// next
 		ctx.Next()
 	}
@@ -218,32 +415,51 @@ func (this *secretManagerImpl) InterceptRsa() gin.HandlerFunc {
  */
 func (this *secretManagerImpl) InterceptAes() gin.HandlerFunc {
 	handler := func(ctx *gin.Context) {
-		if _httpConfig.SecretOpen == false {
+		g := Gin{ctx}
+		config, ok := this.interceptConfig(&g)
+		if !ok {
+			return
+		}
+		if config.SecretOpen == false {
 			ctx.Next()
 			return
 		}
 
-		g := Gin{ctx}
 		var code StatusCode = STATUS_CODE_SUCCESS
 		var params SecretParams
 		var key string
 		var keyBytes []byte
 		var encrypted []byte
 		var jsonBytes []byte
+		var version string
+
+		// app handle
+		if app, err := resolveClientApp(&g, config, APP_SECRET_METHOD_AES); err != nil {
+			code = STATUS_CODE_SECRET_APP_INVALID
+			g.Response(code, code.Msg(),nil, "")
+			ctx.Abort()
+			return
+		} else {
+			g.getRequisition().App = app
+		}
 
 		// params handle
 		if err := g.Ctx.Bind(&params); err != nil { // bind
 			code = STATUS_CODE_SECRET_CHECK_FAILED
+		} else if version, err = secretAesVersion(config.SecretAesMode, ctx.GetHeader(HEADER_SECRET_VERSION)); err != nil { // version
+			code = STATUS_CODE_SECRET_CHECK_FAILED
 		} else if err := validate.ValidateParameter(params); err != nil { // validate
 			code = STATUS_CODE_INVALID_PARAMS
-		} else if key, err = getSecretStorage().Get(foundation.GetRequisition(ctx).UserName); err != nil { // get key
+		} else if key, err = this.keyspace.Get(SECRET_SPACE_AESKEY, g.getRequisition().SessionId); err != nil { // get key
 			code = STATUS_CODE_SECRET_CHECK_FAILED
 		} else if encrypted, err = base64.StdEncoding.DecodeString(params.Secret); err != nil { // decode
 			code = STATUS_CODE_SECRET_CHECK_FAILED
 		} else if keyBytes, err = hex.DecodeString(key); err != nil { // get key bytes
 			code = STATUS_CODE_SECRET_CHECK_FAILED
-		} else if jsonBytes, err = encrypt.GetAes().DecryptCBC(encrypted, keyBytes); err != nil { // decrypt
+		} else if jsonBytes, err = secretAesDecrypt(version, encrypted, keyBytes); err != nil { // decrypt
 			code = STATUS_CODE_SECRET_CHECK_FAILED
+		} else {
+			code = this.checkReplay(config, jsonBytes) // replay check
 		}
 		// code check
 		if code != STATUS_CODE_SUCCESS {
@@ -253,6 +469,7 @@ func (this *secretManagerImpl) InterceptAes() gin.HandlerFunc {
 		}
 
 		g.getRequisition().SecretMethod = secret_method_aes
+		g.getRequisition().SecretVersion = version
 		g.getRequisition().Params = jsonBytes
 		g.getRequisition().Key = keyBytes
 		// next
@@ -260,39 +477,3 @@ func (this *secretManagerImpl) InterceptAes() gin.HandlerFunc {
 	}
 	return handler
 }
-
-
-
-
-/**
-* 获取内存存储实例
-*/
-func NewMemeoryStorage() ISecretStorage {
-	return new(SecretMemeoryStorageImpl)
-}
-
-// 内存存储实现
-type SecretMemeoryStorageImpl struct {
-	memoryStorageMap sync.Map
-}
-
-func (this *SecretMemeoryStorageImpl) Set(key string, value interface{}, expiresTime time.Duration) error {
-	this.memoryStorageMap.Store(key, value)
-	// 定时删除
-	select {
-	case <- time.After(expiresTime):
-		this.Delete(key)
-	}
-	return nil
-}
-
-func (this *SecretMemeoryStorageImpl) Get(key string) (value string, err error) {
-	if tokenInter, ok := this.memoryStorageMap.Load(key); ok {
-		return tokenInter.(string), nil
-	}
-	return "", errors.New("value for key " + key + "not exist")
-}
-
-func (this *SecretMemeoryStorageImpl) Delete(key string) {
-	this.memoryStorageMap.Delete(key)
-}
@@ -1,7 +1,11 @@
 package serving
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sean-tech/gokit/foundation"
 	"testing"
 	"time"
 )
@@ -46,4 +50,45 @@ func TestToken(t *testing.T) {
 		return
 	}
 	fmt.Println("token check success!")
-}
\ No newline at end of file
+}
+
+
+func TestLoginThenAesRequest(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{SecretOpen: true})
+	secretMgr := server.SecretManager()
+	server.Engine().POST("/api/user/v1/info", secretMgr.InterceptToken(), secretMgr.InterceptAes(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseData(map[string]string{"user_name": foundation.GetRequisition(ctx).UserName})
+	})
+
+	// login
+	config := server.Config()
+	token, err := secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenInfo, err := secretMgr.ParseToken(token, config.JwtSecret, config.JwtIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := secretMgr.GetAesKey(tokenInfo.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, _ := hex.DecodeString(key)
+
+	// authenticated aes request
+	_, resp := serveTestRequest(server, "POST", "/api/user/v1/info", map[string]string{"Authorization": token}, aesTestSecret(t, map[string]string{"hello": "world"}, keyBytes))
+	if code := resp["code"].(float64); code != STATUS_CODE_SUCCESS {
+		t.Fatalf("response code %v, msg %v", code, resp["msg"])
+	}
+	var data map[string]string
+	if err := json.Unmarshal(aesTestDecrypt(t, resp["data"], keyBytes), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["user_name"] != "seantest1" {
+		t.Errorf("response user_name %s, expected seantest1", data["user_name"])
+	}
+}
+
+
@@ -3,6 +3,7 @@ package serving
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"github.com/rcrowley/go-metrics"
 	"github.com/sean-tech/gokit/foundation"
@@ -14,6 +15,7 @@ import (
 	"github.com/smallnest/rpcx/serverplugin"
 	"log"
 	"math"
+	"net"
 	"strings"
 	"sync"
 	"time"
@@ -29,142 +31,386 @@ type RpcConfig struct {
 	SecretOpen 				bool   			`json:"secret_open"`
 	ServerCert 				string 			`json:"server_cert" validate:"required,gte=1"`
 	ServerKey  				string			`json:"server_key" validate:"required,gte=1"`
-	ClientCert 				string 			`json:"client_cert" validate:"required,gte=1"`
-	ClientKey  				string 			`json:"client_key" validate:"required,gte=1"`
 	// etcd
 	EtcdRpcBasePath 		string			`json:"etcd_rpc_base_path" validate:"required,gte=1"`
 	EtcdEndPoints 			[]string		`json:"etcd_end_points" validate:"required,gte=1,dive,tcp_addr"`
-	// log
-	Logger 				rpcxLog.Logger
+	// log，请求日志按实例记录，rpcx内部日志为进程级设置，仅首个创建的server生效
+	Logger 				rpcxLog.Logger	`json:"-" validate:"required"`
 }
 /** 服务注册回调函数 **/
 type RpcRegisterFunc func(server *server.Server)
 
 var (
-	_rpcConfig RpcConfig
 	_rpc_testing bool = false
+	_rpcxLoggerOnce sync.Once
 )
 
 /**
- * 启动 服务server
- * registerFunc 服务注册回调函数
+ * rpc server实例，配置、插件均归属实例
  */
-func RpcServerServe(config RpcConfig, registerFunc RpcRegisterFunc) {
+type RpcServer struct {
+	config 			RpcConfig
+	address 		string
+	tlsConfig 		*tls.Config
+	server 			*server.Server
+	etcdPlugin 		*serverplugin.EtcdRegisterPlugin
+	started 		bool
+}
+
+/**
+ * 创建 rpc server，配置或证书错误返回error
+ */
+func NewRpcServer(config RpcConfig) (*RpcServer, error) {
 	if err := validate.ValidateParameter(config); err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	_rpcConfig = config
-
-	rpcxLog.SetLogger(_rpcConfig.Logger)
+	// rpcx内部日志为进程级设置，仅首个创建的server生效
+	_rpcxLoggerOnce.Do(func() {
+		rpcxLog.SetLogger(config.Logger)
+	})
 
-	var s *server.Server
+	this := &RpcServer{
+		config:  config,
+		address: fmt.Sprintf(":%d", config.RpcPort),
+	}
 	if config.SecretOpen {
 		//cert, err := tls.LoadX509KeyPair(config.App.RuntimeRootPath + config.App.TLSCerPath, config.App.RuntimeRootPath + config.App.TLSKeyPath)
 		cert, err := tls.X509KeyPair([]byte(config.ServerCert), []byte(config.ServerKey))
 		if err != nil {
-			log.Fatal(err)
-			return
+			return nil, err
 		}
-		tlsConfig := &tls.Config{
+		this.tlsConfig = &tls.Config{
 			Certificates: []tls.Certificate{cert},
 		}
-		s = server.NewServer(server.WithTLSConfig(tlsConfig))
+		this.server = server.NewServer(server.WithTLSConfig(this.tlsConfig))
 	} else {
-		s = server.NewServer(server.WithReadTimeout(config.ReadTimeout))
+		this.server = server.NewServer(server.WithReadTimeout(config.ReadTimeout))
 	}
 
-	address := fmt.Sprintf(":%d", config.RpcPort)
-	s.Plugins.Add(RpcLogger)
-	RegisterPluginEtcd(s, address)
-	RegisterPluginRateLimit(s)
+	this.server.Plugins.Add(&rpclogger{logger: config.Logger})
+	this.registerPluginEtcd()
+	this.registerPluginRateLimit()
+	return this, nil
+}
 
-	registerFunc(s)
+/**
+ * rpcx server，用于注册服务
+ */
+func (this *RpcServer) Server() *server.Server {
+	return this.server
+}
+
+/**
+ * 启动监听并注册至etcd，失败返回error，成功后异步处理请求
+ */
+func (this *RpcServer) Start() error {
+	listener, err := net.Listen("tcp", this.address)
+	if err != nil {
+		return err
+	}
+	if this.tlsConfig != nil {
+		listener = tls.NewListener(listener, this.tlsConfig)
+	}
+	if this.etcdPlugin != nil {
+		if err := this.etcdPlugin.Start(); err != nil {
+			listener.Close()
+			return err
+		}
+	}
+	ready := &readyListener{Listener: listener, ready: make(chan struct{})}
+	serveErr := make(chan error, 1)
 	go func() {
-		err := s.Serve("tcp", address)
-		if err != nil {
-			log.Fatalf("server start error : %v", err)
+		err := this.server.ServeListener("tcp", ready)
+		if err != nil && err != server.ErrServerClosed {
+			log.Printf("rpc server serve error : %v", err)
 		}
+		serveErr <- err
 	}()
+	// rpcx在serve协程内绑定listener，绑定后开始Accept，此后Shutdown才可用
+	select {
+	case err := <- serveErr:
+		if this.etcdPlugin != nil {
+			this.etcdPlugin.Stop()
+		}
+		if err == nil {
+			err = errors.New("rpc server stopped before listening")
+		}
+		return err
+	case <- ready.ready:
+	}
+	this.started = true
+	return nil
+}
+
+/**
+ * 首次Accept时关闭ready，通知rpcx已完成listener绑定
+ */
+type readyListener struct {
+	net.Listener
+	once 	sync.Once
+	ready 	chan struct{}
+}
+
+func (this *readyListener) Accept() (net.Conn, error) {
+	this.once.Do(func() {
+		close(this.ready)
+	})
+	return this.Listener.Accept()
+}
+
+/**
+ * 优雅关闭，先从etcd注销服务，再等待处理中的调用完成或ctx超时
+ */
+func (this *RpcServer) Shutdown(ctx context.Context) error {
+	if !this.started {
+		return nil
+	}
+	this.started = false
+	var unregisterErr = this.server.UnregisterAll()
+	if this.etcdPlugin != nil {
+		if err := this.etcdPlugin.Stop(); err != nil && unregisterErr == nil {
+			unregisterErr = err
+		}
+	}
+	if err := this.server.Shutdown(ctx); err != nil {
+		return err
+	}
+	return unregisterErr
+}
+
+/**
+ * 启动 服务server
+ * registerFunc 服务注册回调函数
+ */
+func RpcServerServe(config RpcConfig, registerFunc RpcRegisterFunc) {
+	s, err := newGlobalRpcServer(config, registerFunc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		log.Fatalf("server start error : %v", err)
+	}
+}
+
+/**
+ * 创建全局rpc server，注册服务并设为旧版全局api的默认配置
+ */
+func newGlobalRpcServer(config RpcConfig, registerFunc RpcRegisterFunc) (*RpcServer, error) {
+	s, err := NewRpcServer(config)
+	if err != nil {
+		return nil, err
+	}
+	registerFunc(s.Server())
+	setDefaultRpcConfig(config)
+	return s, nil
 }
 
 /**
  * 注册插件，Etcd注册中心，服务发现
  */
-func RegisterPluginEtcd(s *server.Server, serviceAddr string)  {
+func (this *RpcServer) registerPluginEtcd()  {
 	if _rpc_testing == true {
 		plugin := client.InprocessClient
-		s.Plugins.Add(plugin)
+		this.server.Plugins.Add(plugin)
 		return
 	}
-	plugin := &serverplugin.EtcdRegisterPlugin{
-		ServiceAddress: "tcp@" + serviceAddr,
-		EtcdServers:    _rpcConfig.EtcdEndPoints,
-		BasePath:       _rpcConfig.EtcdRpcBasePath,
+	this.etcdPlugin = &serverplugin.EtcdRegisterPlugin{
+		ServiceAddress: "tcp@" + this.address,
+		EtcdServers:    this.config.EtcdEndPoints,
+		BasePath:       this.config.EtcdRpcBasePath,
 		Metrics:        metrics.NewRegistry(),
 		Services:       nil,
 		UpdateInterval: time.Minute,
 		Options:        nil,
 	}
-	err := plugin.Start()
-	if err != nil {
-		log.Fatal(err)
-	}
-	s.Plugins.Add(plugin)
+	this.server.Plugins.Add(this.etcdPlugin)
 }
 
 /**
  * 注册插件，限流器，限制客户端连接数
  */
-func RegisterPluginRateLimit(s *server.Server)  {
-	var fillSpeed float64 = 1.0 / float64(_rpcConfig.RpcPerSecondConnIdle)
+func (this *RpcServer) registerPluginRateLimit()  {
+	var fillSpeed float64 = 1.0 / float64(this.config.RpcPerSecondConnIdle)
 	fillInterval := time.Duration(fillSpeed * math.Pow(10, 9))
-	plugin := serverplugin.NewRateLimitingPlugin(fillInterval, _rpcConfig.RpcPerSecondConnIdle)
-	s.Plugins.Add(plugin)
+	plugin := serverplugin.NewRateLimitingPlugin(fillInterval, this.config.RpcPerSecondConnIdle)
+	this.server.Plugins.Add(plugin)
+}
+
+type RpcClientConfig struct {
+	ReadTimeout           	time.Duration	`json:"read_timeout" validate:"required,gte=1"`
+	WriteTimeout          	time.Duration	`json:"write_timeout" validate:"required,gte=1"`
+	// tls
+	SecretOpen 				bool   			`json:"secret_open"`
+	ClientCert 				string 			`json:"client_cert"`
+	ClientKey  				string 			`json:"client_key"`
+	// etcd
+	EtcdRpcBasePath 		string			`json:"etcd_rpc_base_path" validate:"required,gte=1"`
+	EtcdEndPoints 			[]string		`json:"etcd_end_points" validate:"required,gte=1,dive,tcp_addr"`
+}
+
+/**
+ * rpc调用客户端工厂，仅依赖客户端配置，与server配置无关
+ */
+type RpcClient struct {
+	config 			RpcClientConfig
+	tlsConfig 		*tls.Config
+	discoveryMap 	sync.Map
+}
+
+/**
+ * 创建rpc客户端工厂，配置或证书错误返回error
+ */
+func NewRpcClient(config RpcClientConfig) (*RpcClient, error) {
+	if err := validate.ValidateParameter(config); err != nil {
+		return nil, err
+	}
+	this := &RpcClient{config: config}
+	if config.SecretOpen {
+		this.tlsConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+		if config.ClientCert != "" || config.ClientKey != "" {
+			cert, err := tls.X509KeyPair([]byte(config.ClientCert), []byte(config.ClientKey))
+			if err != nil {
+				return nil, err
+			}
+			this.tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+	return this, nil
 }
 
 /**
  * 创建rpc调用客户端，基于Etcd服务发现
  */
-func CreateRpcClient(serviceName string) client.XClient {
+func (this *RpcClient) Create(serviceName string) client.XClient {
 	option := client.DefaultOption
 	option.Heartbeat = true
 	option.HeartbeatInterval = time.Second
-	option.ReadTimeout = _rpcConfig.ReadTimeout
-	option.WriteTimeout = _rpcConfig.WriteTimeout
-	if _rpcConfig.SecretOpen {
-		option.TLSConfig = &tls.Config{
-			InsecureSkipVerify: true,
-		}
-	}
-	xclient := client.NewXClient(serviceName, client.Failover, client.RoundRobin, *getDiscovery(serviceName), option)
+	option.ReadTimeout = this.config.ReadTimeout
+	option.WriteTimeout = this.config.WriteTimeout
+	option.TLSConfig = this.tlsConfig
+	xclient := client.NewXClient(serviceName, client.Failover, client.RoundRobin, *this.getDiscovery(serviceName), option)
 	return xclient
 }
-var discoveryMap sync.Map
-func getDiscovery(serviceName string) *client.ServiceDiscovery {
-	if discovery, ok := discoveryMap.Load(serviceName); ok {
+
+func (this *RpcClient) getDiscovery(serviceName string) *client.ServiceDiscovery {
+	if discovery, ok := this.discoveryMap.Load(serviceName); ok {
 		return discovery.(*client.ServiceDiscovery)
 	}
 	var discovery client.ServiceDiscovery
 	if _rpc_testing == true {
 		discovery = client.NewInprocessDiscovery()
 	} else {
-		discovery = client.NewEtcdDiscovery(_rpcConfig.EtcdRpcBasePath, serviceName, _rpcConfig.EtcdEndPoints, nil)
+		discovery = client.NewEtcdDiscovery(this.config.EtcdRpcBasePath, serviceName, this.config.EtcdEndPoints, nil)
+	}
+	actual, _ := this.discoveryMap.LoadOrStore(serviceName, &discovery)
+	return actual.(*client.ServiceDiscovery)
+}
+
+var (
+	_rpcConfigLock 	sync.RWMutex
+	_rpcConfig 		RpcConfig
+	_rpcClient 		*RpcClient
+)
+
+/**
+ * RpcServerServe的server配置，供旧版全局api使用
+ */
+func setDefaultRpcConfig(config RpcConfig) {
+	_rpcConfigLock.Lock()
+	defer _rpcConfigLock.Unlock()
+	_rpcConfig = config
+	_rpcClient = nil
+}
+
+func defaultRpcConfig() RpcConfig {
+	_rpcConfigLock.RLock()
+	defer _rpcConfigLock.RUnlock()
+	return _rpcConfig
+}
+
+/**
+ * 注册插件，Etcd注册中心，使用RpcServerServe的server配置
+ * Deprecated: NewRpcServer已注册etcd插件，并在Start时启动
+ */
+func RegisterPluginEtcd(s *server.Server, serviceAddr string)  {
+	if _rpc_testing == true {
+		s.Plugins.Add(client.InprocessClient)
+		return
 	}
-	discoveryMap.Store(serviceName, &discovery)
-	return &discovery
+	config := defaultRpcConfig()
+	plugin := &serverplugin.EtcdRegisterPlugin{
+		ServiceAddress: "tcp@" + serviceAddr,
+		EtcdServers:    config.EtcdEndPoints,
+		BasePath:       config.EtcdRpcBasePath,
+		Metrics:        metrics.NewRegistry(),
+		Services:       nil,
+		UpdateInterval: time.Minute,
+		Options:        nil,
+	}
+	if err := plugin.Start(); err != nil {
+		log.Fatal(err)
+	}
+	s.Plugins.Add(plugin)
 }
 
+/**
+ * 注册插件，限流器，使用RpcServerServe的server配置
+ * Deprecated: NewRpcServer已注册限流插件
+ */
+func RegisterPluginRateLimit(s *server.Server)  {
+	config := defaultRpcConfig()
+	if config.RpcPerSecondConnIdle <= 0 {
+		return
+	}
+	var fillSpeed float64 = 1.0 / float64(config.RpcPerSecondConnIdle)
+	fillInterval := time.Duration(fillSpeed * math.Pow(10, 9))
+	s.Plugins.Add(serverplugin.NewRateLimitingPlugin(fillInterval, config.RpcPerSecondConnIdle))
+}
+
+/**
+ * 创建rpc调用客户端，使用RpcServerServe的server配置
+ * Deprecated: 使用NewRpcClient(RpcClientConfig).Create
+ */
+func CreateRpcClient(serviceName string) client.XClient {
+	_rpcConfigLock.Lock()
+	if _rpcClient == nil {
+		_rpcClient = &RpcClient{config: RpcClientConfig{
+			ReadTimeout:     _rpcConfig.ReadTimeout,
+			WriteTimeout:    _rpcConfig.WriteTimeout,
+			SecretOpen:      _rpcConfig.SecretOpen,
+			EtcdRpcBasePath: _rpcConfig.EtcdRpcBasePath,
+			EtcdEndPoints:   _rpcConfig.EtcdEndPoints,
+		}}
+		if _rpcConfig.SecretOpen {
+			_rpcClient.tlsConfig = &tls.Config{
+				InsecureSkipVerify: true,
+			}
+		}
+	}
+	rpcClient := _rpcClient
+	_rpcConfigLock.Unlock()
+	return rpcClient.Create(serviceName)
+}
+
+/**
+ * rpc日志插件，使用RpcServerServe的server配置的Logger
+ * Deprecated: NewRpcServer已添加日志插件
+ */
+var RpcLogger = &rpclogger{}
+
 type IRpcxLogger interface {
 	Rpcx(v ...interface{})
 	Error(v ...interface{})
 	Errorf(format string, v ...interface{})
 }
 type rpclogger struct {
+	logger rpcxLog.Logger
 }
-var RpcLogger = &rpclogger{}
 
 func (this *rpclogger) PostReadRequest(ctx context.Context, r *protocol.Message, e error) error {
-	logPrint("PostReadRequest", ctx, r, e)
+	this.logPrint("PostReadRequest", ctx, r, e)
 	return nil
 }
 
@@ -176,7 +422,7 @@ func (this *rpclogger) PreWriteResponse(ctx context.Context, req *protocol.Messa
 }
 
 func (this *rpclogger) PostWriteResponse(ctx context.Context, req *protocol.Message, resp *protocol.Message, e error) error {
-	logPrint("PostWriteResponse", ctx, resp, e)
+	this.logPrint("PostWriteResponse", ctx, resp, e)
 	return nil
 }
 
@@ -187,9 +433,16 @@ func (this *rpclogger) PostWriteRequest(ctx context.Context, r *protocol.Message
 	return nil
 }
 
-func logPrint(prefix string, ctx context.Context, msg *protocol.Message, e error)  {
+func (this *rpclogger) logPrint(prefix string, ctx context.Context, msg *protocol.Message, e error)  {
+	var logger = this.logger
+	if logger == nil {
+		// 兼容旧版RpcLogger，使用RpcServerServe的server配置
+		if logger = defaultRpcConfig().Logger; logger == nil {
+			return
+		}
+	}
 	if e != nil {
-		_rpcConfig.Logger.Errorf("[RPCX] %s error:%s", prefix, e.Error())
+		logger.Errorf("[RPCX] %s error:%s", prefix, e.Error())
 		return
 	}
 
@@ -202,9 +455,9 @@ func logPrint(prefix string, ctx context.Context, msg *protocol.Message, e error
 	payload := strings.ToValidUTF8(string(msg.Payload), ":")
 	var info = fmt.Sprintf("%s request_id:%d | user_name:%s | service_call:%s.%s | metadata:%s | payload:%s ",
 		prefix, request_id, user_name, msg.ServicePath, msg.ServiceMethod, msg.Metadata, payload)
-	if logger, ok := _rpcConfig.Logger.(IRpcxLogger); ok {
-		logger.Rpcx(info)
+	if rpcxLogger, ok := logger.(IRpcxLogger); ok {
+		rpcxLogger.Rpcx(info)
 	} else {
-		_rpcConfig.Logger.Infof("[RPCX] %s", info)
+		logger.Infof("[RPCX] %s", info)
 	}
 }
\ No newline at end of file
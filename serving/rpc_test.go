@@ -37,12 +37,10 @@ func (this *userServiceImpl) UserAdd(ctx context.Context, parameter *UserAddPara
 }
 
 func TestRpcServer(t *testing.T) {
-	logging.Setup(logging.LogConfig{
-		LogSavePath:     "/Users/lyra/Desktop/",
-		LogPrefix:       "rpctest",
-	})
+	setupTestLogging()
 	_rpc_testing = true
-	RpcServerServe(RpcConfig{
+	defer setDefaultRpcConfig(RpcConfig{})
+	rpcServer, err := newGlobalRpcServer(RpcConfig{
 		RunMode:              "debug",
 		RpcPort:              9001,
 		RpcPerSecondConnIdle: 500,
@@ -51,17 +49,30 @@ func TestRpcServer(t *testing.T) {
 		SecretOpen:           false,
 		ServerCert:           "1",
 		ServerKey:            "1",
-		ClientCert:           "1",
-		ClientKey:            "1",
 		EtcdRpcBasePath:      "sean.tech/webkit/serving/rpc",
 		EtcdEndPoints:        []string{"127.0.0.1:2379"},
 		Logger:               logging.Logger(),
 	}, func(server *server.Server) {
 		server.RegisterName("User", new(userServiceImpl), "")
 	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rpcServer.Start(); err != nil {
+		t.Fatal(err)
+	}
 
+	rpcClient, err := NewRpcClient(RpcClientConfig{
+		ReadTimeout:     60 * time.Second,
+		WriteTimeout:    60 * time.Second,
+		EtcdRpcBasePath: "sean.tech/webkit/serving/rpc",
+		EtcdEndPoints:   []string{"127.0.0.1:2379"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
 	var user = new(UserInfo)
-	client := CreateRpcClient("User")
+	client := rpcClient.Create("User")
 	if err := client.Call(context.Background(), "UserAdd", &UserAddParameter{
 		UserName: "1237757@qq.com",
 		Password: "Aa123456",
@@ -71,6 +82,22 @@ func TestRpcServer(t *testing.T) {
 		fmt.Printf("user--%+v", user)
 	}
 
+	// 旧版全局api
+	legacyClient := CreateRpcClient("User")
+	defer legacyClient.Close()
+	if err := legacyClient.Call(context.Background(), "UserAdd", &UserAddParameter{
+		UserName: "1237757@qq.com",
+		Password: "Aa123456",
+	}, user); err != nil {
+		t.Errorf("legacy client call failed, %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	if err := rpcServer.Shutdown(ctx); err != nil {
+		t.Error(err)
+	}
+
 	//signal
 	//quit := make(chan os.Signal)
 	//signal.Notify(quit, os.Interrupt)
@@ -79,6 +106,27 @@ func TestRpcServer(t *testing.T) {
 }
 
 
+func TestRpcServerConfigInvalid(t *testing.T) {
+	if _, err := NewRpcServer(RpcConfig{RunMode: "debug"}); err == nil {
+		t.Error("invalid config should return error")
+	}
+	if _, err := NewRpcServer(RpcConfig{
+		RunMode:              "debug",
+		RpcPort:              9002,
+		RpcPerSecondConnIdle: 500,
+		ReadTimeout:          60 * time.Second,
+		WriteTimeout:         60 * time.Second,
+		SecretOpen:           true,
+		ServerCert:           "1",
+		ServerKey:            "1",
+		EtcdRpcBasePath:      "sean.tech/webkit/serving/rpc",
+		EtcdEndPoints:        []string{"127.0.0.1:2379"},
+		Logger:               logging.Logger(),
+	}); err == nil {
+		t.Error("invalid server cert should return error")
+	}
+}
+
 func TestParameter(t *testing.T) {
 	var user = new(UserInfo)
 	if err := UserService.UserAdd(context.Background(), &UserAddParameter{
@@ -0,0 +1,92 @@
+package serving
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"github.com/sean-tech/gokit/encrypt"
+)
+
+const (
+	// aes通道协议版本请求头，缺省为cbc，响应头回写实际使用的版本
+	HEADER_SECRET_VERSION 	= "X-Secret-Version"
+	SECRET_VERSION_CBC 		= "1"
+	SECRET_VERSION_GCM 		= "2"
+)
+
+const (
+	// 仅cbc，默认
+	SECRET_AES_MODE_CBC 		= "cbc"
+	// 仅gcm
+	SECRET_AES_MODE_GCM 		= "gcm"
+	// cbc及gcm均可，按请求头版本选择，用于客户端迁移
+	SECRET_AES_MODE_COMPATIBLE 	= "compatible"
+)
+
+/**
+ * 请求协议版本，未配置或未携带时为cbc，模式不允许时返回error
+ */
+func secretAesVersion(mode string, version string) (string, error) {
+	if version == "" {
+		version = SECRET_VERSION_CBC
+	}
+	switch {
+	case version == SECRET_VERSION_CBC && (mode == "" || mode == SECRET_AES_MODE_CBC || mode == SECRET_AES_MODE_COMPATIBLE):
+		return version, nil
+	case version == SECRET_VERSION_GCM && (mode == SECRET_AES_MODE_GCM || mode == SECRET_AES_MODE_COMPATIBLE):
+		return version, nil
+	}
+	return "", errors.New("secret version " + version + " not allowed in aes mode " + mode)
+}
+
+func secretAesEncrypt(version string, data []byte, key []byte) ([]byte, error) {
+	if version == SECRET_VERSION_GCM {
+		return aesGcmEncrypt(data, key)
+	}
+	return encrypt.GetAes().EncryptCBC(data, key)
+}
+
+func secretAesDecrypt(version string, data []byte, key []byte) ([]byte, error) {
+	if version == SECRET_VERSION_GCM {
+		return aesGcmDecrypt(data, key)
+	}
+	return encrypt.GetAes().DecryptCBC(data, key)
+}
+
+/**
+ * aes-gcm加密，输出为12字节随机nonce拼接密文及tag
+ */
+func aesGcmEncrypt(data []byte, key []byte) ([]byte, error) {
+	gcm, err := newAesGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+/**
+ * aes-gcm解密，校验tag失败返回error
+ */
+func aesGcmDecrypt(data []byte, key []byte) ([]byte, error) {
+	gcm, err := newAesGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() + gcm.Overhead() {
+		return nil, errors.New("aes gcm data too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func newAesGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
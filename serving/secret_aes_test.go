@@ -0,0 +1,120 @@
+package serving
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/sean-tech/gokit/encrypt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAesGcm(t *testing.T) {
+	key, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	encrypted, err := aesGcmEncrypt([]byte("hello world"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted, err := aesGcmDecrypt(encrypted, key); err != nil || string(decrypted) != "hello world" {
+		t.Errorf("decrypted %s, %v", decrypted, err)
+	}
+	encrypted[len(encrypted) - 1] ^= 0xff
+	if _, err := aesGcmDecrypt(encrypted, key); err == nil {
+		t.Error("tampered data should fail to decrypt")
+	}
+}
+
+func TestAesGcmRequest(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{SecretOpen: true, SecretAesMode: SECRET_AES_MODE_COMPATIBLE})
+	secretMgr := server.SecretManager()
+	server.Engine().POST("/api/user/v1/info", secretMgr.InterceptToken(), secretMgr.InterceptAes(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		var parameter map[string]string
+		if err := json.Unmarshal(g.getRequisition().Params, &parameter); err != nil {
+			g.ResponseError(err)
+			return
+		}
+		g.ResponseData(parameter)
+	})
+	config := server.Config()
+	token, _ := secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	tokenInfo, _ := secretMgr.ParseToken(token, config.JwtSecret, config.JwtIssuer)
+	key, _ := secretMgr.GetAesKey(tokenInfo.Id)
+	keyBytes, _ := hex.DecodeString(key)
+	request := func(version string, secret []byte) (*httptest.ResponseRecorder, map[string]interface{}) {
+		body, _ := json.Marshal(map[string]string{"secret": base64.StdEncoding.EncodeToString(secret)})
+		req := httptest.NewRequest("POST", "/api/user/v1/info", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+		if version != "" {
+			req.Header.Set(HEADER_SECRET_VERSION, version)
+		}
+		recorder := httptest.NewRecorder()
+		server.Engine().ServeHTTP(recorder, req)
+		var resp map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &resp)
+		return recorder, resp
+	}
+
+	// 旧客户端cbc
+	if _, resp := serveTestRequest(server, "POST", "/api/user/v1/info", map[string]string{"Authorization": token}, aesTestSecret(t, map[string]string{"hello": "cbc"}, keyBytes)); resp["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Fatalf("cbc code %v, expected success", resp["code"])
+	} else if string(aesTestDecrypt(t, resp["data"], keyBytes)) != `{"hello":"cbc"}` {
+		t.Errorf("cbc response %v", resp["data"])
+	}
+
+	// 新客户端gcm
+	secret, _ := aesGcmEncrypt([]byte(`{"hello":"gcm"}`), keyBytes)
+	recorder, resp := request(SECRET_VERSION_GCM, secret)
+	if resp["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Fatalf("gcm code %v, expected success", resp["code"])
+	}
+	if version := recorder.Header().Get(HEADER_SECRET_VERSION); version != SECRET_VERSION_GCM {
+		t.Errorf("response version %s, expected %s", version, SECRET_VERSION_GCM)
+	}
+	encrypted, _ := base64.StdEncoding.DecodeString(resp["data"].(string))
+	if decrypted, err := aesGcmDecrypt(encrypted, keyBytes); err != nil || string(decrypted) != `{"hello":"gcm"}` {
+		t.Errorf("gcm response %s, %v", decrypted, err)
+	}
+
+	// 篡改
+	secret[len(secret) - 1] ^= 0xff
+	if _, resp := request(SECRET_VERSION_GCM, secret); resp["code"].(float64) != STATUS_CODE_SECRET_CHECK_FAILED {
+		t.Errorf("tampered code %v, expected %d", resp["code"], STATUS_CODE_SECRET_CHECK_FAILED)
+	}
+
+	// 仅cbc模式拒绝gcm
+	if _, err := secretAesVersion(SECRET_AES_MODE_CBC, SECRET_VERSION_GCM); err == nil {
+		t.Error("cbc mode should reject gcm version")
+	}
+	if _, err := secretAesVersion(SECRET_AES_MODE_GCM, ""); err == nil {
+		t.Error("gcm mode should reject cbc version")
+	}
+}
+
+func aesTestSecret(t *testing.T, parameter interface{}, keyBytes []byte) map[string]string {
+	jsonBytes, _ := json.Marshal(parameter)
+	encrypted, err := encrypt.GetAes().EncryptCBC(jsonBytes, keyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]string{"secret": base64.StdEncoding.EncodeToString(encrypted)}
+}
+
+func aesTestDecrypt(t *testing.T, data interface{}, keyBytes []byte) []byte {
+	secret, ok := data.(string)
+	if !ok {
+		t.Fatalf("response data %v is not encrypted", data)
+	}
+	encrypted, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonBytes, err := encrypt.GetAes().DecryptCBC(encrypted, keyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jsonBytes
+}
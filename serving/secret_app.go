@@ -0,0 +1,167 @@
+package serving
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/sean-tech/gokit/validate"
+	"sync"
+)
+
+const (
+	// 客户端应用id请求头
+	HEADER_APP_ID = "X-App-Id"
+	// 应用可用的加密方式
+	APP_SECRET_METHOD_RSA = "rsa"
+	APP_SECRET_METHOD_AES = "aes"
+	// 应用状态
+	APP_STATUS_ENABLED  = "enabled"
+	APP_STATUS_DISABLED = "disabled"
+)
+
+/**
+ * 客户端应用，如iOS、Android及合作方接入，各自持有rsa密钥对
+ */
+type ClientApp struct {
+	AppId 			string		`json:"appId" validate:"required,gte=1"`
+	ClientPubKey 	string		`json:"clientPubKey"`
+	// 允许的加密方式，为空时不限制
+	SecretMethods 	[]string	`json:"secretMethods" validate:"dive,oneof=rsa aes"`
+	Status 			string		`json:"status" validate:"required,oneof=enabled disabled"`
+}
+
+/**
+ * 应用是否可使用加密方式
+ */
+func (this *ClientApp) Allowed(secretMethod string) bool {
+	if this.Status != APP_STATUS_ENABLED {
+		return false
+	}
+	if len(this.SecretMethods) == 0 {
+		return true
+	}
+	for _, method := range this.SecretMethods {
+		if method == secretMethod {
+			return true
+		}
+	}
+	return false
+}
+
+/** 应用注册表接口 **/
+type IAppRegistry interface {
+	GetApp(appId string) (*ClientApp, error)
+}
+
+/**
+ * 静态应用注册表，取自配置
+ */
+func NewStaticAppRegistry(apps []ClientApp) (*StaticAppRegistry, error) {
+	this := &StaticAppRegistry{apps: make(map[string]*ClientApp, len(apps))}
+	for i := range apps {
+		if err := validate.ValidateParameter(apps[i]); err != nil {
+			return nil, err
+		}
+		app := apps[i]
+		this.apps[app.AppId] = &app
+	}
+	return this, nil
+}
+
+type StaticAppRegistry struct {
+	apps map[string]*ClientApp
+}
+
+func (this *StaticAppRegistry) GetApp(appId string) (*ClientApp, error) {
+	if app, ok := this.apps[appId]; ok {
+		return app, nil
+	}
+	return nil, errors.New("app " + appId + " not exist")
+}
+
+/**
+ * 存储应用注册表，应用信息存于storage，多副本间共享，可运行时注册及停用
+ */
+func NewStorageAppRegistry(storage ISecretStorage, keyPrefix string) *StorageAppRegistry {
+	return &StorageAppRegistry{keyspace: NewSecretKeyspace(storage, keyPrefix)}
+}
+
+type StorageAppRegistry struct {
+	keyspace 	*SecretKeyspace
+	lock 		sync.Mutex
+}
+
+func (this *StorageAppRegistry) GetApp(appId string) (*ClientApp, error) {
+	value, err := this.keyspace.Get(SECRET_SPACE_APP, appId)
+	if err != nil {
+		return nil, err
+	}
+	var app ClientApp
+	if err := json.Unmarshal([]byte(value), &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+/**
+ * 注册或更新应用，永不过期
+ */
+func (this *StorageAppRegistry) Register(app ClientApp) error {
+	if err := validate.ValidateParameter(app); err != nil {
+		return err
+	}
+	jsonBytes, err := json.Marshal(app)
+	if err != nil {
+		return err
+	}
+	return this.keyspace.Set(SECRET_SPACE_APP, app.AppId, string(jsonBytes), 0)
+}
+
+/**
+ * 更新应用状态
+ */
+func (this *StorageAppRegistry) SetStatus(appId string, status string) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	app, err := this.GetApp(appId)
+	if err != nil {
+		return err
+	}
+	app.Status = status
+	return this.Register(*app)
+}
+
+func (this *StorageAppRegistry) Remove(appId string) {
+	this.keyspace.Delete(SECRET_SPACE_APP, appId)
+}
+
+/**
+ * 请求应用解析，携带X-App-Id时须为注册表中允许该加密方式的应用，返回应用客户端公钥
+ * 未携带时使用HttpConfig.ClientPubKey，兼容单应用接入
+ */
+func resolveClientApp(g *Gin, config *HttpConfig, secretMethod string) (*ClientApp, error) {
+	appId := g.Ctx.GetHeader(HEADER_APP_ID)
+	if appId == "" {
+		return nil, nil
+	}
+	if config.AppRegistry == nil {
+		return nil, errors.New("app registry not configured")
+	}
+	app, err := config.AppRegistry.GetApp(appId)
+	if err != nil {
+		return nil, err
+	}
+	if !app.Allowed(secretMethod) {
+		return nil, errors.New("app " + appId + " not allowed secret method " + secretMethod)
+	}
+	return app, nil
+}
+
+/**
+ * 当前请求的客户端公钥
+ */
+func (g *Gin) clientPubKey(config *HttpConfig) string {
+	if app := g.getRequisition().App; app != nil {
+		return app.ClientPubKey
+	}
+	return config.ClientPubKey
+}
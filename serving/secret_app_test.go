@@ -0,0 +1,87 @@
+package serving
+
+import (
+	"encoding/base64"
+	"github.com/gin-gonic/gin"
+	"github.com/sean-tech/gokit/encrypt"
+	"testing"
+)
+
+func TestClientAppRsaRequest(t *testing.T) {
+	serverPubKey, serverPriKey := rsaTestKeyPair(t)
+	iosPubKey, iosPriKey := rsaTestKeyPair(t)
+	partnerPubKey, partnerPriKey := rsaTestKeyPair(t)
+	registry, err := NewStaticAppRegistry([]ClientApp{
+		{AppId: "ios", ClientPubKey: iosPubKey, Status: APP_STATUS_ENABLED},
+		{AppId: "android", SecretMethods: []string{APP_SECRET_METHOD_AES}, Status: APP_STATUS_ENABLED},
+		{AppId: "partner", ClientPubKey: partnerPubKey, Status: APP_STATUS_DISABLED},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newSecretTestServer(t, HttpConfig{
+		SecretOpen:   true,
+		ServerPubKey: serverPubKey,
+		ServerPriKey: serverPriKey,
+		AppRegistry:  registry,
+	})
+	server.Engine().POST("/api/user/v1/info", server.SecretManager().InterceptRsa(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseData(map[string]string{"hello": "world"})
+	})
+	request := func(appId string, clientPriKey string) map[string]interface{} {
+		jsonBytes := []byte(`{"user_name":"seantest1"}`)
+		secret, _ := encrypt.GetRsa().Encrypt(serverPubKey, jsonBytes)
+		signBytes, _ := encrypt.GetRsa().Sign(clientPriKey, jsonBytes)
+		_, resp := serveTestRequest(server, "POST", "/api/user/v1/info", map[string]string{
+			HEADER_APP_ID: appId,
+			"sign":        base64.StdEncoding.EncodeToString(signBytes),
+		}, map[string]string{"secret": base64.StdEncoding.EncodeToString(secret)})
+		return resp
+	}
+
+	resp := request("ios", iosPriKey)
+	if resp["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Fatalf("ios code %v, expected success", resp["code"])
+	}
+	encrypted, _ := base64.StdEncoding.DecodeString(resp["data"].(string))
+	if decrypted, err := encrypt.GetRsa().Decrypt(iosPriKey, encrypted); err != nil || string(decrypted) != `{"hello":"world"}` {
+		t.Errorf("ios response %s, %v", decrypted, err)
+	}
+	// 其他应用私钥签名
+	if resp := request("ios", partnerPriKey); resp["code"].(float64) != STATUS_CODE_SECRET_CHECK_FAILED {
+		t.Errorf("wrong key code %v, expected %d", resp["code"], STATUS_CODE_SECRET_CHECK_FAILED)
+	}
+	for _, appId := range []string{"android", "partner", "unknown"} {
+		if resp := request(appId, partnerPriKey); resp["code"].(float64) != STATUS_CODE_SECRET_APP_INVALID {
+			t.Errorf("%s code %v, expected %d", appId, resp["code"], STATUS_CODE_SECRET_APP_INVALID)
+		}
+	}
+}
+
+func TestStorageAppRegistry(t *testing.T) {
+	registry := NewStorageAppRegistry(NewMemeoryStorage(), "app1")
+	if err := registry.Register(ClientApp{AppId: "ios", Status: "unknown"}); err == nil {
+		t.Error("invalid status should return error")
+	}
+	if err := registry.Register(ClientApp{AppId: "ios", ClientPubKey: "pubkey", Status: APP_STATUS_ENABLED}); err != nil {
+		t.Fatal(err)
+	}
+	app, err := registry.GetApp("ios")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.ClientPubKey != "pubkey" || !app.Allowed(APP_SECRET_METHOD_RSA) {
+		t.Errorf("app %+v", app)
+	}
+	if err := registry.SetStatus("ios", APP_STATUS_DISABLED); err != nil {
+		t.Fatal(err)
+	}
+	if app, _ := registry.GetApp("ios"); app.Allowed(APP_SECRET_METHOD_RSA) {
+		t.Error("disabled app should not be allowed")
+	}
+	registry.Remove("ios")
+	if _, err := registry.GetApp("ios"); err == nil {
+		t.Error("removed app should not exist")
+	}
+}
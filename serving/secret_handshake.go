@@ -0,0 +1,134 @@
+package serving
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/sean-tech/gokit/encrypt"
+	"github.com/sean-tech/gokit/foundation"
+	"github.com/sean-tech/gokit/validate"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"time"
+)
+
+// 会话aes key派生信息，客户端须一致
+const handshake_hkdf_info = "webkit/serving/handshake/aes-key"
+
+/**
+ * 握手参数，客户端X25519公钥
+ */
+type HandshakeParams struct {
+	PublicKey string 	`json:"publicKey" validate:"required,base64"`
+}
+
+/**
+ * 握手结果，服务端临时X25519公钥
+ */
+type HandshakeResult struct {
+	PublicKey string 	`json:"publicKey"`
+}
+
+/**
+ * 生成X25519密钥对
+ */
+func GenerateX25519KeyPair() (publicKey []byte, privateKey []byte, err error) {
+	privateKey = make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(privateKey); err != nil {
+		return nil, nil, err
+	}
+	if publicKey, err = curve25519.X25519(privateKey, curve25519.Basepoint); err != nil {
+		return nil, nil, err
+	}
+	return publicKey, privateKey, nil
+}
+
+/**
+ * 由己方私钥及对方公钥派生会话aes key，HKDF-SHA256，salt为会话id，双方结果一致
+ */
+func DeriveAesKey(privateKey []byte, peerPublicKey []byte, sessionId string) ([]byte, error) {
+	shared, err := curve25519.X25519(privateKey, peerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, []byte(sessionId), []byte(handshake_hkdf_info)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+/**
+ * 密钥交换，以客户端公钥派生会话aes key并替换存储，返回服务端临时公钥
+ */
+func (this *secretManagerImpl) ExchangeAesKey(sessionId string, clientPublicKey []byte, expiration time.Duration) ([]byte, error) {
+	if _, err := this.keyspace.Get(SECRET_SPACE_TOKEN, sessionId); err != nil {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
+	}
+	serverPublicKey, serverPrivateKey, err := GenerateX25519KeyPair()
+	if err != nil {
+		return nil, err
+	}
+	key, err := DeriveAesKey(serverPrivateKey, clientPublicKey, sessionId)
+	if err != nil {
+		return nil, foundation.NewError(STATUS_CODE_SECRET_CHECK_FAILED, STATUS_MSG_SECRET_CHECK_FAILED)
+	}
+	if err := this.keyspace.Set(SECRET_SPACE_AESKEY, sessionId, hex.EncodeToString(key), expiration); err != nil {
+		return nil, err
+	}
+	return serverPublicKey, nil
+}
+
+/**
+ * 握手接口，需在InterceptToken之后，派生的aes key绑定当前会话，有效期同token
+ * 配置服务端密钥时以当前密钥对响应数据签名，客户端以对应公钥校验防止中间人替换公钥
+ */
+func (this *secretManagerImpl) HandshakeHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		g := Gin{ctx}
+		config, ok := this.interceptConfig(&g)
+		if !ok {
+			return
+		}
+		tokenInfo := g.TokenInfo()
+		if tokenInfo == nil {
+			g.ResponseError(foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_EMPTY, STATUS_MSG_AUTH_CHECK_TOKEN_EMPTY))
+			return
+		}
+		var params HandshakeParams
+		if err := ctx.Bind(&params); err != nil {
+			g.ResponseError(foundation.NewError(STATUS_CODE_INVALID_PARAMS, err.Error()))
+			return
+		}
+		if err := validate.ValidateParameter(params); err != nil {
+			g.ResponseError(foundation.NewError(STATUS_CODE_INVALID_PARAMS, err.Error()))
+			return
+		}
+		clientPublicKey, _ := base64.StdEncoding.DecodeString(params.PublicKey)
+		serverPublicKey, err := this.ExchangeAesKey(tokenInfo.Id, clientPublicKey, time.Until(time.Unix(tokenInfo.ExpiresAt, 0)))
+		if err != nil {
+			g.ResponseError(err)
+			return
+		}
+		result := HandshakeResult{PublicKey: base64.StdEncoding.EncodeToString(serverPublicKey)}
+		var sign = ""
+		if serverKey := config.currentServerKey(); serverKey.PriKey != "" {
+			jsonBytes, _ := json.Marshal(result)
+			signBytes, err := encrypt.GetRsa().Sign(serverKey.PriKey, jsonBytes)
+			if err != nil {
+				g.ResponseError(foundation.NewError(STATUS_CODE_ERROR, err.Error()))
+				return
+			}
+			sign = base64.StdEncoding.EncodeToString(signBytes)
+			if serverKey.Version != "" {
+				ctx.Header(HEADER_KEY_VERSION, serverKey.Version)
+			}
+		}
+		var code StatusCode = STATUS_CODE_SUCCESS
+		g.Response(code, code.Msg(), result, sign)
+	}
+}
@@ -0,0 +1,71 @@
+package serving
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/sean-tech/gokit/encrypt"
+	"testing"
+)
+
+func TestHandshakeThenAesRequest(t *testing.T) {
+	serverPubKey, serverPriKey := rsaTestKeyPair(t)
+	server := newSecretTestServer(t, HttpConfig{SecretOpen: true, ServerPubKey: serverPubKey, ServerPriKey: serverPriKey})
+	secretMgr := server.SecretManager()
+	server.Engine().POST("/api/user/v1/handshake", secretMgr.InterceptToken(), secretMgr.HandshakeHandler())
+	server.Engine().POST("/api/user/v1/info", secretMgr.InterceptToken(), secretMgr.InterceptAes(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseData(map[string]string{"user_name": g.TokenInfo().UserName})
+	})
+
+	config := server.Config()
+	token, _ := secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	tokenInfo, _ := secretMgr.ParseToken(token, config.JwtSecret, config.JwtIssuer)
+
+	// handshake
+	clientPublicKey, clientPrivateKey, err := GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, resp := serveTestRequest(server, "POST", "/api/user/v1/handshake", map[string]string{"Authorization": token}, HandshakeParams{
+		PublicKey: base64.StdEncoding.EncodeToString(clientPublicKey),
+	})
+	if resp["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Fatalf("handshake code %v, expected success", resp["code"])
+	}
+	data := resp["data"].(map[string]interface{})
+	jsonBytes, _ := json.Marshal(HandshakeResult{PublicKey: data["publicKey"].(string)})
+	signBytes, _ := base64.StdEncoding.DecodeString(resp["sign"].(string))
+	if err := encrypt.GetRsa().Verify(serverPubKey, jsonBytes, signBytes); err != nil {
+		t.Fatalf("handshake sign verify failed, %v", err)
+	}
+	serverPublicKey, _ := base64.StdEncoding.DecodeString(data["publicKey"].(string))
+	keyBytes, err := DeriveAesKey(clientPrivateKey, serverPublicKey, tokenInfo.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 双方派生的key一致
+	_, resp = serveTestRequest(server, "POST", "/api/user/v1/info", map[string]string{"Authorization": token}, aesTestSecret(t, map[string]string{"hello": "world"}, keyBytes))
+	if resp["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Fatalf("aes code %v, expected success", resp["code"])
+	}
+	if jsonBytes := aesTestDecrypt(t, resp["data"], keyBytes); string(jsonBytes) != `{"user_name":"seantest1"}` {
+		t.Errorf("aes response %s", jsonBytes)
+	}
+
+	// 非法公钥
+	_, resp = serveTestRequest(server, "POST", "/api/user/v1/handshake", map[string]string{"Authorization": token}, HandshakeParams{
+		PublicKey: base64.StdEncoding.EncodeToString(make([]byte, 32)),
+	})
+	if resp["code"].(float64) != STATUS_CODE_SECRET_CHECK_FAILED {
+		t.Errorf("low order public key code %v, expected %d", resp["code"], STATUS_CODE_SECRET_CHECK_FAILED)
+	}
+	// 未登录
+	_, resp = serveTestRequest(server, "POST", "/api/user/v1/handshake", nil, HandshakeParams{
+		PublicKey: base64.StdEncoding.EncodeToString(clientPublicKey),
+	})
+	if resp["code"].(float64) != STATUS_CODE_AUTH_CHECK_TOKEN_EMPTY {
+		t.Errorf("no token code %v, expected %d", resp["code"], STATUS_CODE_AUTH_CHECK_TOKEN_EMPTY)
+	}
+}
@@ -0,0 +1,398 @@
+package serving
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sean-tech/gokit/foundation"
+	"math/big"
+	"time"
+)
+
+const (
+	JWT_ALGORITHM_HS256 = "HS256"
+	JWT_ALGORITHM_RS256 = "RS256"
+	JWT_ALGORITHM_ES256 = "ES256"
+	JWT_ALGORITHM_EDDSA = "EdDSA"
+)
+
+type JwtKeyConfig struct {
+	Kid 		string		`json:"kid" validate:"required,gte=1"`
+	Algorithm 	string		`json:"algorithm" validate:"required,oneof=RS256 ES256 EdDSA"`
+	// pem格式私钥(PKCS8/PKCS1/SEC1)，为空时该密钥仅用于校验
+	PrivateKey 	string		`json:"private_key"`
+	// pem格式公钥(PKIX)，为空时由私钥导出
+	PublicKey 	string		`json:"public_key"`
+}
+
+type jwtKey struct {
+	kid 		string
+	method 		jwt.SigningMethod
+	privateKey 	crypto.Signer
+	publicKey 	crypto.PublicKey
+}
+
+/**
+ * jwt非对称密钥集，使用当前签名密钥签发，按token头kid选择校验密钥，支持多密钥并存轮换
+ */
+type JwtKeySet struct {
+	keys 		map[string]*jwtKey
+	kids 		[]string
+	signing 	*jwtKey
+}
+
+/**
+ * 创建密钥集，signingKid为空时使用第一个含私钥的密钥签名，均无私钥时仅可校验
+ */
+func NewJwtKeySet(configs []JwtKeyConfig, signingKid string) (*JwtKeySet, error) {
+	var keys = make([]*jwtKey, 0, len(configs))
+	for _, config := range configs {
+		key, err := newJwtKey(config)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	this, err := newJwtKeySet(keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.privateKey == nil {
+			continue
+		}
+		if signingKid == "" || signingKid == key.kid {
+			this.signing = key
+			break
+		}
+	}
+	if signingKid != "" && this.signing == nil {
+		return nil, errors.New("jwt signing key " + signingKid + " not exist or has no private key")
+	}
+	return this, nil
+}
+
+/**
+ * 由jwks文档创建仅校验的密钥集，供其他服务无私钥校验token
+ */
+func ParseJwks(data []byte) (*JwtKeySet, error) {
+	var jwks Jwks
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+	var keys = make([]*jwtKey, 0, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		key, err := jwk.toJwtKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return newJwtKeySet(keys)
+}
+
+func newJwtKeySet(keys []*jwtKey) (*JwtKeySet, error) {
+	this := &JwtKeySet{
+		keys: make(map[string]*jwtKey, len(keys)),
+		kids: make([]string, 0, len(keys)),
+	}
+	for _, key := range keys {
+		if _, ok := this.keys[key.kid]; ok {
+			return nil, errors.New("jwt key " + key.kid + " duplicated")
+		}
+		this.keys[key.kid] = key
+		this.kids = append(this.kids, key.kid)
+	}
+	return this, nil
+}
+
+func newJwtKey(config JwtKeyConfig) (*jwtKey, error) {
+	key := &jwtKey{
+		kid:    config.Kid,
+		method: jwt.GetSigningMethod(config.Algorithm),
+	}
+	if key.method == nil || config.Algorithm == JWT_ALGORITHM_HS256 {
+		return nil, errors.New("jwt key " + config.Kid + " algorithm " + config.Algorithm + " not support")
+	}
+	if config.PrivateKey != "" {
+		privateKey, err := parseJwtPrivateKey(config.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		key.privateKey = privateKey
+		key.publicKey = privateKey.Public()
+	}
+	if config.PublicKey != "" {
+		block, _ := pem.Decode([]byte(config.PublicKey))
+		if block == nil {
+			return nil, errors.New("jwt key " + config.Kid + " public key pem decode failed")
+		}
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.publicKey = publicKey
+	}
+	if key.publicKey == nil {
+		return nil, errors.New("jwt key " + config.Kid + " has no key")
+	}
+	if !jwtKeyTypeMatched(config.Algorithm, key.publicKey) {
+		return nil, errors.New("jwt key " + config.Kid + " type not match algorithm " + config.Algorithm)
+	}
+	return key, nil
+}
+
+func parseJwtPrivateKey(pemString string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(pemString))
+	if block == nil {
+		return nil, errors.New("jwt private key pem decode failed")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("jwt private key type not support")
+}
+
+func jwtKeyTypeMatched(algorithm string, publicKey crypto.PublicKey) bool {
+	switch algorithm {
+	case JWT_ALGORITHM_RS256:
+		_, ok := publicKey.(*rsa.PublicKey)
+		return ok
+	case JWT_ALGORITHM_ES256:
+		key, ok := publicKey.(*ecdsa.PublicKey)
+		return ok && key.Curve == elliptic.P256()
+	case JWT_ALGORITHM_EDDSA:
+		_, ok := publicKey.(ed25519.PublicKey)
+		return ok
+	}
+	return false
+}
+
+/**
+ * 是否可签名
+ */
+func (this *JwtKeySet) CanSign() bool {
+	return this.signing != nil
+}
+
+/**
+ * 使用当前签名密钥签发，token头写入kid
+ */
+func (this *JwtKeySet) Sign(claims jwt.Claims) (string, error) {
+	if this.signing == nil {
+		return "", errors.New("jwt key set has no signing key")
+	}
+	token := jwt.NewWithClaims(this.signing.method, claims)
+	token.Header["kid"] = this.signing.kid
+	return token.SignedString(this.signing.privateKey)
+}
+
+/**
+ * jwt校验密钥获取，按kid查找并校验算法一致
+ */
+func (this *JwtKeySet) KeyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := this.keys[kid]
+	if !ok {
+		return nil, errors.New("jwt key " + kid + " not exist")
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("jwt key " + kid + " algorithm not match")
+	}
+	return key.publicKey, nil
+}
+
+/**
+ * 无状态解析token，仅校验签名、签发者及过期时间，不校验会话是否注销
+ */
+func (this *JwtKeySet) ParseToken(token string, JwtIssuer string) (*TokenInfo, error) {
+	if token == "" {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_EMPTY, STATUS_MSG_AUTH_CHECK_TOKEN_EMPTY)
+	}
+	tokenClaims, err := jwt.ParseWithClaims(token, &TokenInfo{}, this.KeyFunc)
+	if err != nil || tokenClaims == nil || !tokenClaims.Valid {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
+	}
+	claims, ok := tokenClaims.Claims.(*TokenInfo)
+	if !ok {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_TYPE_ERROR, STATUS_MSG_AUTH_TYPE_ERROR)
+	}
+	if claims.Issuer != JwtIssuer {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_TIMEOUT, STATUS_MSG_AUTH_CHECK_TOKEN_TIMEOUT)
+	}
+	return claims, nil
+}
+
+/**
+ * 导出全部公钥为jwks文档
+ */
+func (this *JwtKeySet) Jwks() *Jwks {
+	jwks := &Jwks{Keys: make([]*Jwk, 0, len(this.kids))}
+	for _, kid := range this.kids {
+		jwks.Keys = append(jwks.Keys, newJwk(this.keys[kid]))
+	}
+	return jwks
+}
+
+/** jwks文档，RFC 7517 **/
+type Jwks struct {
+	Keys []*Jwk 		`json:"keys"`
+}
+
+type Jwk struct {
+	Kty string 			`json:"kty"`
+	Kid string 			`json:"kid"`
+	Use string 			`json:"use,omitempty"`
+	Alg string 			`json:"alg,omitempty"`
+	// rsa
+	N 	string 			`json:"n,omitempty"`
+	E 	string 			`json:"e,omitempty"`
+	// ec & okp
+	Crv string 			`json:"crv,omitempty"`
+	X 	string 			`json:"x,omitempty"`
+	Y 	string 			`json:"y,omitempty"`
+}
+
+func newJwk(key *jwtKey) *Jwk {
+	jwk := &Jwk{
+		Kid: key.kid,
+		Use: "sig",
+		Alg: key.method.Alg(),
+	}
+	switch publicKey := key.publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(padJwkBytes(publicKey.X.Bytes(), size))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(padJwkBytes(publicKey.Y.Bytes(), size))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	}
+	return jwk
+}
+
+func padJwkBytes(data []byte, size int) []byte {
+	if len(data) >= size {
+		return data
+	}
+	padded := make([]byte, size)
+	copy(padded[size - len(data):], data)
+	return padded
+}
+
+func (this *Jwk) toJwtKey() (*jwtKey, error) {
+	key := &jwtKey{
+		kid:    this.Kid,
+		method: jwt.GetSigningMethod(this.Alg),
+	}
+	if key.method == nil || this.Alg == JWT_ALGORITHM_HS256 {
+		return nil, errors.New("jwk " + this.Kid + " algorithm " + this.Alg + " not support")
+	}
+	decode := base64.RawURLEncoding.DecodeString
+	switch this.Kty {
+	case "RSA":
+		n, err := decode(this.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(this.E)
+		if err != nil {
+			return nil, err
+		}
+		key.publicKey = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		if this.Crv != elliptic.P256().Params().Name {
+			return nil, errors.New("jwk " + this.Kid + " curve " + this.Crv + " not support")
+		}
+		x, err := decode(this.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(this.Y)
+		if err != nil {
+			return nil, err
+		}
+		key.publicKey = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	case "OKP":
+		x, err := decode(this.X)
+		if err != nil {
+			return nil, err
+		}
+		if this.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("jwk " + this.Kid + " curve " + this.Crv + " not support")
+		}
+		key.publicKey = ed25519.PublicKey(x)
+	default:
+		return nil, errors.New("jwk " + this.Kid + " type " + this.Kty + " not support")
+	}
+	if !jwtKeyTypeMatched(this.Alg, key.publicKey) {
+		return nil, errors.New("jwk " + this.Kid + " type not match algorithm " + this.Alg)
+	}
+	return key, nil
+}
+
+/**
+ * EdDSA(Ed25519)签名方法，jwt-go v3未内置
+ */
+type signingMethodEdDSA struct{}
+
+var SigningMethodEdDSA = &signingMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(JWT_ALGORITHM_EDDSA, func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (this *signingMethodEdDSA) Alg() string {
+	return JWT_ALGORITHM_EDDSA
+}
+
+func (this *signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+func (this *signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package serving
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func jwtTestPrivateKey(t *testing.T, algorithm string) string {
+	var key interface{}
+	var err error
+	switch algorithm {
+	case JWT_ALGORITHM_RS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case JWT_ALGORITHM_ES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case JWT_ALGORITHM_EDDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestJwtAsymmetricSigning(t *testing.T) {
+	var keys = []JwtKeyConfig{
+		{Kid: "rs-1", Algorithm: JWT_ALGORITHM_RS256, PrivateKey: jwtTestPrivateKey(t, JWT_ALGORITHM_RS256)},
+		{Kid: "es-1", Algorithm: JWT_ALGORITHM_ES256, PrivateKey: jwtTestPrivateKey(t, JWT_ALGORITHM_ES256)},
+		{Kid: "ed-1", Algorithm: JWT_ALGORITHM_EDDSA, PrivateKey: jwtTestPrivateKey(t, JWT_ALGORITHM_EDDSA)},
+	}
+	storage := NewMemeoryStorage()
+	for _, key := range keys {
+		keySet, err := NewJwtKeySet(keys, key.Kid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secretMgr := NewSecretManager(SecretManagerConfig{Storage: storage, JwtKeySet: keySet})
+		token, err := secretMgr.GenerateToken(1230090123, "seantest1", false, "", "sean.test", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, _ := jwt.Parse(token, keySet.KeyFunc)
+		if parsed == nil || parsed.Header["kid"] != key.Kid || parsed.Method.Alg() != key.Algorithm {
+			t.Fatalf("token %s not signed by %s", token, key.Kid)
+		}
+		if err := secretMgr.CheckToken(token, "", "sean.test"); err != nil {
+			t.Errorf("%s: %v", key.Kid, err)
+		}
+	}
+
+	// 轮换后旧kid签发的token仍可校验
+	oldKeySet, _ := NewJwtKeySet(keys, "rs-1")
+	newKeySet, _ := NewJwtKeySet(keys, "ed-1")
+	token, _ := NewSecretManager(SecretManagerConfig{Storage: storage, JwtKeySet: oldKeySet}).GenerateToken(1230090123, "seantest2", false, "", "sean.test", time.Hour)
+	if err := NewSecretManager(SecretManagerConfig{Storage: storage, JwtKeySet: newKeySet}).CheckToken(token, "", "sean.test"); err != nil {
+		t.Error(err)
+	}
+	// 移除旧kid后不可校验
+	removedKeySet, _ := NewJwtKeySet(keys[1:], "ed-1")
+	if err := NewSecretManager(SecretManagerConfig{Storage: storage, JwtKeySet: removedKeySet}).CheckToken(token, "", "sean.test"); err == nil {
+		t.Error("token signed by removed key should be rejected")
+	}
+
+	if _, err := NewJwtKeySet(keys, "not-exist"); err == nil {
+		t.Error("not exist signing kid should return error")
+	}
+	if _, err := NewJwtKeySet([]JwtKeyConfig{{Kid: "rs-1", Algorithm: JWT_ALGORITHM_ES256, PrivateKey: keys[0].PrivateKey}}, ""); err == nil {
+		t.Error("key type not match algorithm should return error")
+	}
+}
+
+func TestJwksHandler(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{
+		JwtKeys: []JwtKeyConfig{
+			{Kid: "es-1", Algorithm: JWT_ALGORITHM_ES256, PrivateKey: jwtTestPrivateKey(t, JWT_ALGORITHM_ES256)},
+			{Kid: "ed-1", Algorithm: JWT_ALGORITHM_EDDSA, PrivateKey: jwtTestPrivateKey(t, JWT_ALGORITHM_EDDSA)},
+		},
+		JwtSigningKid: "ed-1",
+	})
+	secretMgr := server.SecretManager()
+	server.Engine().GET("/.well-known/jwks.json", secretMgr.JwksHandler())
+
+	config := server.Config()
+	token, err := secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.Engine().ServeHTTP(recorder, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	var jwks Jwks
+	if err := json.Unmarshal(recorder.Body.Bytes(), &jwks); err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kty != "EC" || jwks.Keys[1].Kty != "OKP" {
+		t.Fatalf("jwks %s", recorder.Body.String())
+	}
+
+	// 其他服务仅凭jwks校验
+	verifier, err := ParseJwks(recorder.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verifier.CanSign() {
+		t.Error("jwks key set should not sign")
+	}
+	tokenInfo, err := verifier.ParseToken(token, config.JwtIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokenInfo.UserName != "seantest1" {
+		t.Errorf("user name %s, expected seantest1", tokenInfo.UserName)
+	}
+	if _, err := verifier.ParseToken(token, "other.issuer"); err == nil {
+		t.Error("other issuer should be rejected")
+	}
+}
@@ -0,0 +1,77 @@
+package serving
+
+import (
+	"strings"
+	"time"
+)
+
+type SecretSpace string
+const (
+	_ SecretSpace 				= ""
+	SECRET_SPACE_TOKEN 			= "token"
+	SECRET_SPACE_TOKEN_PREVIOUS = "prevtoken"
+	SECRET_SPACE_AESKEY 		= "aeskey"
+	SECRET_SPACE_REFRESH 		= "refresh"
+	SECRET_SPACE_REFRESH_USED 	= "refreshused"
+	SECRET_SPACE_SESSIONS 		= "sessions"
+	SECRET_SPACE_SESSIONS_LOCK 	= "sessionslock"
+	SECRET_SPACE_REVOKED 		= "revoked"
+	SECRET_SPACE_NONCE 			= "nonce"
+	SECRET_SPACE_APP 			= "app"
+)
+
+/**
+ * 存储键空间，按用途划分存储键(如 token:<sessionId>、aeskey:<sessionId>、sessions:<user>)，避免不同数据相互覆盖
+ * prefix 用于多应用共享同一存储时区分，如 app1:token:<sessionId>
+ */
+type SecretKeyspace struct {
+	storage ISecretStorage
+	prefix 	string
+}
+
+/**
+ * 创建键空间
+ */
+func NewSecretKeyspace(storage ISecretStorage, prefix string) *SecretKeyspace {
+	return &SecretKeyspace{
+		storage: storage,
+		prefix:  strings.TrimSuffix(prefix, ":"),
+	}
+}
+
+/**
+ * 完整存储键
+ */
+func (this *SecretKeyspace) Key(space SecretSpace, id string) string {
+	if this.prefix == "" {
+		return string(space) + ":" + id
+	}
+	return this.prefix + ":" + string(space) + ":" + id
+}
+
+func (this *SecretKeyspace) Set(space SecretSpace, id string, value interface{}, expiration time.Duration) error {
+	return this.storage.Set(this.Key(space, id), value, expiration)
+}
+
+/**
+ * 键不存在时写入，storage未实现ISecretStorageSetNX时退化为非原子的先读后写
+ */
+func (this *SecretKeyspace) SetNX(space SecretSpace, id string, value interface{}, expiration time.Duration) (bool, error) {
+	if storage, ok := this.storage.(ISecretStorageSetNX); ok {
+		return storage.SetNX(this.Key(space, id), value, expiration)
+	}
+	if _, err := this.storage.Get(this.Key(space, id)); err == nil {
+		return false, nil
+	} else if !IsSecretNotExist(err) {
+		return false, err
+	}
+	return true, this.storage.Set(this.Key(space, id), value, expiration)
+}
+
+func (this *SecretKeyspace) Get(space SecretSpace, id string) (string, error) {
+	return this.storage.Get(this.Key(space, id))
+}
+
+func (this *SecretKeyspace) Delete(space SecretSpace, id string) {
+	this.storage.Delete(this.Key(space, id))
+}
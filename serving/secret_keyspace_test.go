@@ -0,0 +1,32 @@
+package serving
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSecretKeyspace(t *testing.T) {
+	var storage = NewMemeoryStorage()
+	var secretMgr = NewSecretManager(SecretManagerConfig{Storage: storage, KeyPrefix: "app1"})
+	token, err := secretMgr.GenerateToken(1230090123, "seantest1", false, "ahsjdadusba", "sean.test", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenInfo, err := secretMgr.ParseToken(token, "ahsjdadusba", "sean.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := storage.Get("app1:token:" + tokenInfo.Id); saved != token {
+		t.Errorf("token stored as %s, expected %s", saved, token)
+	}
+	key, err := secretMgr.GetAesKey(tokenInfo.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := storage.Get("app1:aeskey:" + tokenInfo.Id); saved != key || key == token {
+		t.Errorf("aes key stored as %s, got %s", saved, key)
+	}
+	if err := secretMgr.CheckToken(token, "ahsjdadusba", "sean.test"); err != nil {
+		t.Error(err)
+	}
+}
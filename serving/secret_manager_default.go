@@ -0,0 +1,151 @@
+package serving
+
+import (
+	"github.com/gin-gonic/gin"
+	"sync"
+	"time"
+)
+
+var (
+	_secretManagerLock   sync.RWMutex
+	_secretManager       ISecretManager
+	_memorySecretManager ISecretManager
+	_memorySecretOnce    sync.Once
+	_defaultSecretManager = &defaultSecretManager{}
+)
+
+/**
+ * 默认secret manager，委托至HttpServerServe创建的server的manager(同其存储及配置)，未经其创建server时为进程内存存储
+ * 拦截器按请求所属server的manager处理
+ * Deprecated: 经NewHttpServer创建的server请使用HttpServer.SecretManager()
+ */
+func GetSecretManager() ISecretManager {
+	return _defaultSecretManager
+}
+
+/**
+ * 设置默认manager，仅全局api(HttpServerServe)调用，nil时恢复为内存存储
+ */
+func setDefaultSecretManager(manager ISecretManager) {
+	_secretManagerLock.Lock()
+	defer _secretManagerLock.Unlock()
+	_secretManager = manager
+}
+
+type defaultSecretManager struct {}
+
+func (this *defaultSecretManager) current() ISecretManager {
+	_secretManagerLock.RLock()
+	manager := _secretManager
+	_secretManagerLock.RUnlock()
+	if manager != nil {
+		return manager
+	}
+	_memorySecretOnce.Do(func() {
+		_memorySecretManager = NewSecretManager(SecretManagerConfig{})
+	})
+	return _memorySecretManager
+}
+
+/**
+ * 请求所属server的manager，无server时为默认
+ */
+func (this *defaultSecretManager) request(ctx *gin.Context) ISecretManager {
+	g := Gin{ctx}
+	if server := g.getServer(); server != nil {
+		return server.SecretManager()
+	}
+	return this.current()
+}
+
+func (this *defaultSecretManager) GenerateToken(userId uint64, userName string, isAdministrotor bool, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration) (string, error) {
+	return this.current().GenerateToken(userId, userName, isAdministrotor, JwtSecret, JwtIssuer, JwtExpiresTime)
+}
+
+func (this *defaultSecretManager) GenerateTokenWithClaims(userId uint64, userName string, isAdministrotor bool, claims map[string]interface{}, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration) (string, error) {
+	return this.current().GenerateTokenWithClaims(userId, userName, isAdministrotor, claims, JwtSecret, JwtIssuer, JwtExpiresTime)
+}
+
+func (this *defaultSecretManager) ParseToken(token string, JwtSecret string, JwtIssuer string) (*TokenInfo, error) {
+	return this.current().ParseToken(token, JwtSecret, JwtIssuer)
+}
+
+func (this *defaultSecretManager) CheckToken(token string, JwtSecret string, JwtIssuer string) error {
+	return this.current().CheckToken(token, JwtSecret, JwtIssuer)
+}
+
+func (this *defaultSecretManager) RenewToken(token string, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration) (string, error) {
+	return this.current().RenewToken(token, JwtSecret, JwtIssuer, JwtExpiresTime)
+}
+
+func (this *defaultSecretManager) GetAesKey(sessionId string) (key string, err error) {
+	return this.current().GetAesKey(sessionId)
+}
+
+func (this *defaultSecretManager) ExchangeAesKey(sessionId string, clientPublicKey []byte, expiration time.Duration) (serverPublicKey []byte, err error) {
+	return this.current().ExchangeAesKey(sessionId, clientPublicKey, expiration)
+}
+
+func (this *defaultSecretManager) GenerateTokenPair(userId uint64, userName string, isAdministrotor bool, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration, RefreshExpiresTime time.Duration) (*TokenPair, error) {
+	return this.current().GenerateTokenPair(userId, userName, isAdministrotor, JwtSecret, JwtIssuer, JwtExpiresTime, RefreshExpiresTime)
+}
+
+func (this *defaultSecretManager) GenerateTokenPairWithClaims(userId uint64, userName string, isAdministrotor bool, claims map[string]interface{}, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration, RefreshExpiresTime time.Duration) (*TokenPair, error) {
+	return this.current().GenerateTokenPairWithClaims(userId, userName, isAdministrotor, claims, JwtSecret, JwtIssuer, JwtExpiresTime, RefreshExpiresTime)
+}
+
+func (this *defaultSecretManager) RefreshToken(refreshToken string, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration, RefreshExpiresTime time.Duration) (*TokenPair, error) {
+	return this.current().RefreshToken(refreshToken, JwtSecret, JwtIssuer, JwtExpiresTime, RefreshExpiresTime)
+}
+
+func (this *defaultSecretManager) ListSessions(userName string) ([]*Session, error) {
+	return this.current().ListSessions(userName)
+}
+
+func (this *defaultSecretManager) RevokeSession(userName string, sessionId string) error {
+	return this.current().RevokeSession(userName, sessionId)
+}
+
+func (this *defaultSecretManager) RevokeUser(userName string) error {
+	return this.current().RevokeUser(userName)
+}
+
+func (this *defaultSecretManager) RevokeToken(token string, JwtSecret string, JwtIssuer string) error {
+	return this.current().RevokeToken(token, JwtSecret, JwtIssuer)
+}
+
+func (this *defaultSecretManager) JwksHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		this.request(ctx).JwksHandler()(ctx)
+	}
+}
+
+func (this *defaultSecretManager) HandshakeHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		this.request(ctx).HandshakeHandler()(ctx)
+	}
+}
+
+func (this *defaultSecretManager) ServerPubKeyHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		this.request(ctx).ServerPubKeyHandler()(ctx)
+	}
+}
+
+func (this *defaultSecretManager) InterceptToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		this.request(ctx).InterceptToken()(ctx)
+	}
+}
+
+func (this *defaultSecretManager) InterceptRsa() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		this.request(ctx).InterceptRsa()(ctx)
+	}
+}
+
+func (this *defaultSecretManager) InterceptAes() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		this.request(ctx).InterceptAes()(ctx)
+	}
+}
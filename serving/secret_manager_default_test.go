@@ -0,0 +1,40 @@
+package serving
+
+import (
+	"github.com/gin-gonic/gin"
+	"testing"
+)
+
+func TestDefaultSecretManager(t *testing.T) {
+	storage := NewMemeoryStorage()
+	defer setDefaultSecretManager(nil)
+	server, err := newGlobalHttpServer(newSecretTestConfig(HttpConfig{SecretStorage: storage}), func(engine *gin.Engine) {
+		// 全局拦截器
+		engine.POST("/api/user/v1/info", GetSecretManager().InterceptToken(), func(ctx *gin.Context) {
+			g := Gin{ctx}
+			g.ResponseData(nil)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := server.Config()
+	token, err := GetSecretManager().GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 使用server配置的存储
+	if err := NewSecretManager(SecretManagerConfig{Storage: storage}).CheckToken(token, config.JwtSecret, config.JwtIssuer); err != nil {
+		t.Errorf("token not saved in server storage, %v", err)
+	}
+	if _, resp := serveTestRequest(server, "POST", "/api/user/v1/info", map[string]string{"Authorization": token}, nil); resp["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Errorf("global intercept code %v", resp["code"])
+	}
+
+	// 经NewHttpServer创建的server不替换默认manager
+	newSecretTestServer(t, HttpConfig{})
+	if err := GetSecretManager().CheckToken(token, config.JwtSecret, config.JwtIssuer); err != nil {
+		t.Errorf("default manager replaced by another server, %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package serving
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/sean-tech/gokit/foundation"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * token对，access token用于接口校验，refresh token用于换取新的token对
+ */
+type TokenPair struct {
+	AccessToken 	string		`json:"accessToken"`
+	RefreshToken 	string		`json:"refreshToken"`
+}
+
+/**
+ * refresh token族，即一次登录会话内轮换产生的refresh token，仅最新一代有效
+ * refresh token为 <sessionId>.<generation>.<hmac(key, sessionId.generation)>，可据key验证历代token确为签发
+ */
+type refreshTokenFamily struct {
+	UserId 			uint64		`json:"userId"`
+	UserName 		string		`json:"userName"`
+	IsAdministrotor bool 		`json:"isAdministrotor,omitempty"`
+	Claims 			map[string]interface{} 	`json:"claims,omitempty"`
+	Key 			string		`json:"key"`
+	Generation 		int64		`json:"generation"`
+}
+
+/**
+ * 指定代的refresh token签名
+ */
+func (this *refreshTokenFamily) mac(sessionId string, generation int64) string {
+	h := hmac.New(sha256.New, []byte(this.Key))
+	h.Write([]byte(sessionId + "." + strconv.FormatInt(generation, 10)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+/**
+ * 生成token对，登录时调用，会话有效期延长至refresh token过期
+ */
+func (this *secretManagerImpl) GenerateTokenPair(userId uint64, userName string, isAdministrotor bool, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration, RefreshExpiresTime time.Duration) (*TokenPair, error) {
+	return this.GenerateTokenPairWithClaims(userId, userName, isAdministrotor, nil, JwtSecret, JwtIssuer, JwtExpiresTime, RefreshExpiresTime)
+}
+
+/**
+ * 生成携带自定义claims的token对，刷新后claims保持不变
+ */
+func (this *secretManagerImpl) GenerateTokenPairWithClaims(userId uint64, userName string, isAdministrotor bool, claims map[string]interface{}, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration, RefreshExpiresTime time.Duration) (*TokenPair, error) {
+	sessionId, accessToken, err := this.createSession(TokenInfo{
+		UserId:          userId,
+		UserName:        userName,
+		IsAdministrotor: isAdministrotor,
+		Claims:          claims,
+	}, JwtSecret, JwtIssuer, JwtExpiresTime)
+	if err != nil {
+		return nil, err
+	}
+	if err := this.renewSession(userName, sessionId, time.Now().Add(RefreshExpiresTime).Unix()); err != nil {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_TOKEN_GENERATE_FAILED, STATUS_MSG_AUTH_TOKEN_GENERATE_FAILED)
+	}
+	refreshToken, err := this.rotateRefreshToken(sessionId, &refreshTokenFamily{
+		UserId:          userId,
+		UserName:        userName,
+		IsAdministrotor: isAdministrotor,
+		Claims:          claims,
+	}, RefreshExpiresTime)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+/**
+ * 刷新token，轮换refresh token并签发同会话的新access token
+ * 已轮换的历代refresh token再次使用视为泄露，注销整个会话，伪造或无效的token仅返回失败
+ * 同一refresh token并发刷新时仅一个成功
+ */
+func (this *secretManagerImpl) RefreshToken(refreshToken string, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration, RefreshExpiresTime time.Duration) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_EMPTY, STATUS_MSG_AUTH_CHECK_TOKEN_EMPTY)
+	}
+	parts := strings.SplitN(refreshToken, ".", 3)
+	if len(parts) != 3 {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_REFRESH_TOKEN_FAILED, STATUS_MSG_AUTH_REFRESH_TOKEN_FAILED)
+	}
+	sessionId, mac := parts[0], parts[2]
+	generation, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_REFRESH_TOKEN_FAILED, STATUS_MSG_AUTH_REFRESH_TOKEN_FAILED)
+	}
+	value, err := this.keyspace.Get(SECRET_SPACE_REFRESH, sessionId)
+	if err != nil {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_REFRESH_TOKEN_FAILED, STATUS_MSG_AUTH_REFRESH_TOKEN_FAILED)
+	}
+	var family refreshTokenFamily
+	if err := json.Unmarshal([]byte(value), &family); err != nil {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_REFRESH_TOKEN_FAILED, STATUS_MSG_AUTH_REFRESH_TOKEN_FAILED)
+	}
+	// 非本族签发或尚未签发的代
+	if generation > family.Generation || subtle.ConstantTimeCompare([]byte(mac), []byte(family.mac(sessionId, generation))) != 1 {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_REFRESH_TOKEN_FAILED, STATUS_MSG_AUTH_REFRESH_TOKEN_FAILED)
+	}
+	// reuse detect，已轮换的历代token
+	if generation < family.Generation {
+		if err := this.RevokeSession(family.UserName, sessionId); err != nil {
+			this.deleteSessionData(sessionId)
+		}
+		return nil, foundation.NewError(STATUS_CODE_AUTH_REFRESH_TOKEN_REUSED, STATUS_MSG_AUTH_REFRESH_TOKEN_REUSED)
+	}
+	// 当前代仅可消费一次，并发刷新时后到者失败；此后签发失败时释放标记，客户端可用同一token重试
+	usedId := sessionId + "." + parts[1]
+	if ok, err := this.keyspace.SetNX(SECRET_SPACE_REFRESH_USED, usedId, 1, RefreshExpiresTime); err != nil || !ok {
+		return nil, foundation.NewError(STATUS_CODE_AUTH_REFRESH_TOKEN_FAILED, STATUS_MSG_AUTH_REFRESH_TOKEN_FAILED)
+	}
+	// 会话已被注销、淘汰或存储故障
+	if err := this.renewSession(family.UserName, sessionId, time.Now().Add(RefreshExpiresTime).Unix()); err != nil {
+		this.keyspace.Delete(SECRET_SPACE_REFRESH_USED, usedId)
+		return nil, foundation.NewError(STATUS_CODE_AUTH_REFRESH_TOKEN_FAILED, STATUS_MSG_AUTH_REFRESH_TOKEN_FAILED)
+	}
+	accessToken, err := this.signToken(sessionId, TokenInfo{
+		UserId:          family.UserId,
+		UserName:        family.UserName,
+		IsAdministrotor: family.IsAdministrotor,
+		Claims:          family.Claims,
+	}, JwtSecret, JwtIssuer, JwtExpiresTime)
+	if err != nil {
+		this.keyspace.Delete(SECRET_SPACE_REFRESH_USED, usedId)
+		return nil, err
+	}
+	// rotate，最后写入新一代，写入前失败时旧token仍为当前代
+	newRefreshToken, err := this.rotateRefreshToken(sessionId, &family, RefreshExpiresTime)
+	if err != nil {
+		this.keyspace.Delete(SECRET_SPACE_REFRESH_USED, usedId)
+		return nil, err
+	}
+	// aes key跟随access token续期
+	if key, err := this.keyspace.Get(SECRET_SPACE_AESKEY, sessionId); err == nil {
+		this.keyspace.Set(SECRET_SPACE_AESKEY, sessionId, key, JwtExpiresTime)
+	}
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+	}, nil
+}
+
+/**
+ * 生成下一代refresh token并存储为族内唯一有效代，新族生成签名key
+ */
+func (this *secretManagerImpl) rotateRefreshToken(sessionId string, family *refreshTokenFamily, RefreshExpiresTime time.Duration) (string, error) {
+	if family.Key == "" {
+		key, err := randomHex(32)
+		if err != nil {
+			return "", foundation.NewError(STATUS_CODE_AUTH_TOKEN_GENERATE_FAILED, STATUS_MSG_AUTH_TOKEN_GENERATE_FAILED)
+		}
+		family.Key = key
+	}
+	family.Generation++
+	jsonBytes, _ := json.Marshal(family)
+	if err := this.keyspace.Set(SECRET_SPACE_REFRESH, sessionId, string(jsonBytes), RefreshExpiresTime); err != nil {
+		return "", foundation.NewError(STATUS_CODE_AUTH_TOKEN_GENERATE_FAILED, STATUS_MSG_AUTH_TOKEN_GENERATE_FAILED)
+	}
+	return sessionId + "." + strconv.FormatInt(family.Generation, 10) + "." + family.mac(sessionId, family.Generation), nil
+}
@@ -0,0 +1,174 @@
+package serving
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	var secret = "ahsjdadusba"
+	var issuer = "sean.test"
+	secretMgr := NewSecretManager(SecretManagerConfig{})
+
+	pair, err := secretMgr.GenerateTokenPair(1230090123, "seantest1", false, secret, issuer, time.Hour, 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := secretMgr.CheckToken(pair.AccessToken, secret, issuer); err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed, err := secretMgr.RefreshToken(pair.RefreshToken, secret, issuer, time.Hour, 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.RefreshToken == pair.RefreshToken {
+		t.Error("refresh token should be rotated")
+	}
+	if err := secretMgr.CheckToken(refreshed.AccessToken, secret, issuer); err != nil {
+		t.Error(err)
+	}
+	tokenInfo, _ := secretMgr.ParseToken(refreshed.AccessToken, secret, issuer)
+	if _, err := secretMgr.GetAesKey(tokenInfo.Id); err != nil {
+		t.Error("aes key should survive refresh:", err)
+	}
+
+	// rotated again
+	refreshed, err = secretMgr.RefreshToken(refreshed.RefreshToken, secret, issuer, time.Hour, 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := secretMgr.CheckToken(refreshed.AccessToken, secret, issuer); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRefreshTokenReuse(t *testing.T) {
+	var secret = "ahsjdadusba"
+	var issuer = "sean.test"
+	secretMgr := NewSecretManager(SecretManagerConfig{})
+
+	pair, err := secretMgr.GenerateTokenPair(1230090123, "seantest1", false, secret, issuer, time.Hour, 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := secretMgr.RefreshToken(pair.RefreshToken, secret, issuer, time.Hour, 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 旧refresh token重放，整族注销
+	_, err = secretMgr.RefreshToken(pair.RefreshToken, secret, issuer, time.Hour, 24 * time.Hour)
+	if e, ok := err.(CError); !ok || e.Code() != STATUS_CODE_AUTH_REFRESH_TOKEN_REUSED {
+		t.Fatalf("reuse should be detected, got %v", err)
+	}
+	if _, err := secretMgr.RefreshToken(refreshed.RefreshToken, secret, issuer, time.Hour, 24 * time.Hour); err == nil {
+		t.Error("latest refresh token of revoked family should be rejected")
+	}
+	if err := secretMgr.CheckToken(refreshed.AccessToken, secret, issuer); err == nil {
+		t.Error("access token of revoked family should be rejected")
+	}
+}
+
+func TestRefreshTokenForged(t *testing.T) {
+	var secret = "ahsjdadusba"
+	var issuer = "sean.test"
+	secretMgr := NewSecretManager(SecretManagerConfig{})
+	pair, err := secretMgr.GenerateTokenPair(1230090123, "seantest1", false, secret, issuer, time.Hour, 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 仅凭access token中可见的会话id伪造，不得注销会话
+	sessionId := strings.SplitN(pair.RefreshToken, ".", 2)[0]
+	for _, forged := range []string{sessionId + ".garbage", sessionId + ".0.garbage", sessionId + ".1.garbage", sessionId + ".2.garbage"} {
+		_, err := secretMgr.RefreshToken(forged, secret, issuer, time.Hour, 24 * time.Hour)
+		if e, ok := err.(CError); !ok || e.Code() != STATUS_CODE_AUTH_REFRESH_TOKEN_FAILED {
+			t.Errorf("forged %s should fail without reuse, got %v", forged, err)
+		}
+	}
+	if err := secretMgr.CheckToken(pair.AccessToken, secret, issuer); err != nil {
+		t.Errorf("session should survive forged refresh, %v", err)
+	}
+	if _, err := secretMgr.RefreshToken(pair.RefreshToken, secret, issuer, time.Hour, 24 * time.Hour); err != nil {
+		t.Errorf("valid refresh token should still work, %v", err)
+	}
+}
+
+func TestRefreshTokenConcurrent(t *testing.T) {
+	var secret = "ahsjdadusba"
+	var issuer = "sean.test"
+	secretMgr := NewSecretManager(SecretManagerConfig{})
+	pair, err := secretMgr.GenerateTokenPair(1230090123, "seantest1", false, secret, issuer, time.Hour, 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wait sync.WaitGroup
+	var lock sync.Mutex
+	var succeeded int
+	for i := 0; i < 8; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			if _, err := secretMgr.RefreshToken(pair.RefreshToken, secret, issuer, time.Hour, 24 * time.Hour); err == nil {
+				lock.Lock()
+				succeeded++
+				lock.Unlock()
+			}
+		}()
+	}
+	wait.Wait()
+	if succeeded != 1 {
+		t.Errorf("concurrent refresh succeeded %d times, expected 1", succeeded)
+	}
+}
+
+/** refresh token写入故障的存储 **/
+type refreshFailedStorage struct {
+	ISecretStorage
+	failed bool
+}
+
+func (this *refreshFailedStorage) Set(key string, value interface{}, expiration time.Duration) error {
+	if this.failed && strings.HasPrefix(key, SECRET_SPACE_REFRESH + ":") {
+		return errors.New("storage unavailable")
+	}
+	return this.ISecretStorage.Set(key, value, expiration)
+}
+
+func TestRefreshTokenRotateFailed(t *testing.T) {
+	var secret = "ahsjdadusba"
+	var issuer = "sean.test"
+	storage := &refreshFailedStorage{ISecretStorage: NewMemeoryStorage()}
+	secretMgr := NewSecretManager(SecretManagerConfig{Storage: storage})
+	pair, err := secretMgr.GenerateTokenPair(1230090123, "seantest1", false, secret, issuer, time.Hour, 24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮换失败后同一token可重试，不视为重放
+	storage.failed = true
+	if _, err := secretMgr.RefreshToken(pair.RefreshToken, secret, issuer, time.Hour, 24 * time.Hour); err == nil {
+		t.Fatal("refresh should fail when rotation fails")
+	}
+	storage.failed = false
+	refreshed, err := secretMgr.RefreshToken(pair.RefreshToken, secret, issuer, time.Hour, 24 * time.Hour)
+	if err != nil {
+		t.Fatalf("retry after failed rotation, %v", err)
+	}
+	if err := secretMgr.CheckToken(refreshed.AccessToken, secret, issuer); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRefreshTokenInvalid(t *testing.T) {
+	secretMgr := NewSecretManager(SecretManagerConfig{})
+	for _, token := range []string{"", "malformed", "unknownfamily.secret"} {
+		if _, err := secretMgr.RefreshToken(token, "ahsjdadusba", "sean.test", time.Hour, time.Hour); err == nil {
+			t.Errorf("refresh token %q should be rejected", token)
+		}
+	}
+}
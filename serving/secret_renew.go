@@ -0,0 +1,56 @@
+package serving
+
+import (
+	"github.com/sean-tech/gokit/foundation"
+	"time"
+)
+
+const (
+	// 滑动续期时新token所在的响应头
+	HEADER_RENEWED_TOKEN = "X-Renewed-Token"
+	// 续期后旧token的宽限期，兼容客户端续期前已发出的并发请求
+	token_renew_grace_time = 30 * time.Second
+)
+
+/**
+ * token续期，签发同会话的新token并延长会话及aes key有效期，旧token在宽限期内仍有效
+ * 仅当前有效token可续期，宽限期内的旧token续期返回error
+ */
+func (this *secretManagerImpl) RenewToken(token string, JwtSecret string, JwtIssuer string, JwtExpiresTime time.Duration) (string, error) {
+	tokenInfo, err := this.ParseToken(token, JwtSecret, JwtIssuer)
+	if err != nil {
+		return "", err
+	}
+	this.renewLock.Lock()
+	defer this.renewLock.Unlock()
+	if savedToken, err := this.keyspace.Get(SECRET_SPACE_TOKEN, tokenInfo.Id); err != nil || savedToken != token {
+		return "", foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
+	}
+	if err := this.renewSession(tokenInfo.UserName, tokenInfo.Id, time.Now().Add(JwtExpiresTime).Unix()); err != nil {
+		return "", foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, STATUS_MSG_AUTH_CHECK_TOKEN_FAILED)
+	}
+	renewedToken, err := this.signToken(tokenInfo.Id, TokenInfo{
+		UserId:          tokenInfo.UserId,
+		UserName:        tokenInfo.UserName,
+		IsAdministrotor: tokenInfo.IsAdministrotor,
+		Claims:          tokenInfo.Claims,
+	}, JwtSecret, JwtIssuer, JwtExpiresTime)
+	if err != nil {
+		return "", err
+	}
+	this.keyspace.Set(SECRET_SPACE_TOKEN_PREVIOUS, tokenInfo.Id, token, token_renew_grace_time)
+	if key, err := this.keyspace.Get(SECRET_SPACE_AESKEY, tokenInfo.Id); err == nil {
+		this.keyspace.Set(SECRET_SPACE_AESKEY, tokenInfo.Id, key, JwtExpiresTime)
+	}
+	return renewedToken, nil
+}
+
+/**
+ * 滑动续期判断，剩余有效期不足renewBefore时需续期，renewBefore为0时取有效期的一半
+ */
+func tokenNeedRenew(tokenInfo *TokenInfo, JwtExpiresTime time.Duration, renewBefore time.Duration) bool {
+	if renewBefore <= 0 {
+		renewBefore = JwtExpiresTime / 2
+	}
+	return time.Until(time.Unix(tokenInfo.ExpiresAt, 0)) < renewBefore
+}
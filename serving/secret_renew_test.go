@@ -0,0 +1,67 @@
+package serving
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSlidingRenewToken(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{
+		JwtSlidingOpen:        true,
+		JwtSlidingRenewBefore: 30 * time.Minute,
+	})
+	secretMgr := server.SecretManager()
+	server.Engine().POST("/api/user/v1/info", secretMgr.InterceptToken(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseData(nil)
+	})
+	config := server.Config()
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/user/v1/info", nil)
+		req.Header.Set("Authorization", token)
+		recorder := httptest.NewRecorder()
+		server.Engine().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// 剩余有效期充足不续期
+	token, _ := secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	if renewed := request(token).Header().Get(HEADER_RENEWED_TOKEN); renewed != "" {
+		t.Error("token far from expiry should not be renewed")
+	}
+
+	// 临近过期续期
+	token, _ = secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, 10 * time.Minute)
+	tokenInfo, _ := secretMgr.ParseToken(token, config.JwtSecret, config.JwtIssuer)
+	renewed := request(token).Header().Get(HEADER_RENEWED_TOKEN)
+	if renewed == "" {
+		t.Fatal("token close to expiry should be renewed")
+	}
+	renewedInfo, err := secretMgr.ParseToken(renewed, config.JwtSecret, config.JwtIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewedInfo.Id != tokenInfo.Id || renewedInfo.ExpiresAt <= tokenInfo.ExpiresAt {
+		t.Errorf("renewed token session %s expires %d, origin %s expires %d", renewedInfo.Id, renewedInfo.ExpiresAt, tokenInfo.Id, tokenInfo.ExpiresAt)
+	}
+	if sessions, _ := secretMgr.ListSessions("seantest1"); sessions[len(sessions) - 1].ExpiresAt < renewedInfo.ExpiresAt {
+		t.Error("session expiry should be extended")
+	}
+	// 宽限期内旧token仍可用，但不再续期
+	recorder := request(token)
+	if recorder.Header().Get(HEADER_RENEWED_TOKEN) != "" {
+		t.Error("previous token should not be renewed again")
+	}
+	if _, err := secretMgr.ParseToken(token, config.JwtSecret, config.JwtIssuer); err != nil {
+		t.Errorf("previous token in grace time should be valid, %v", err)
+	}
+	// 注销后旧token及新token均失效
+	if err := secretMgr.RevokeToken(renewed, config.JwtSecret, config.JwtIssuer); err != nil {
+		t.Fatal(err)
+	}
+	if err := secretMgr.CheckToken(token, config.JwtSecret, config.JwtIssuer); err == nil {
+		t.Error("previous token of revoked session should be rejected")
+	}
+}
@@ -0,0 +1,48 @@
+package serving
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const default_secret_replay_window = 5 * time.Minute
+
+/**
+ * 防重放参数，位于加密参数明文内，受加密及签名保护，客户端参数结构可内嵌
+ */
+type SecretReplayParams struct {
+	// unix秒
+	Timestamp 	int64		`json:"timestamp"`
+	// 随机串，窗口内不可重复
+	Nonce 		string		`json:"nonce"`
+}
+
+/**
+ * 防重放校验，时间偏差超出窗口或nonce已使用时返回STATUS_CODE_SECRET_REPLAYED
+ * nonce记录至时间戳超出窗口后过期
+ */
+func (this *secretManagerImpl) checkReplay(config *HttpConfig, jsonBytes []byte) StatusCode {
+	if config.SecretReplayOpen == false {
+		return STATUS_CODE_SUCCESS
+	}
+	window := config.SecretReplayWindow
+	if window <= 0 {
+		window = default_secret_replay_window
+	}
+	var params SecretReplayParams
+	if err := json.Unmarshal(jsonBytes, &params); err != nil || params.Nonce == "" || len(params.Nonce) > 64 || params.Timestamp <= 0 {
+		return STATUS_CODE_SECRET_CHECK_FAILED
+	}
+	offset := time.Since(time.Unix(params.Timestamp, 0))
+	if offset > window || offset < -window {
+		return STATUS_CODE_SECRET_REPLAYED
+	}
+	stored, err := this.keyspace.SetNX(SECRET_SPACE_NONCE, params.Nonce, params.Timestamp, window - offset + time.Second)
+	if err != nil {
+		return STATUS_CODE_ERROR
+	}
+	if !stored {
+		return STATUS_CODE_SECRET_REPLAYED
+	}
+	return STATUS_CODE_SUCCESS
+}
@@ -0,0 +1,76 @@
+package serving
+
+import (
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSecretReplay(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{
+		SecretOpen:         true,
+		SecretReplayOpen:   true,
+		SecretReplayWindow: time.Minute,
+	})
+	secretMgr := server.SecretManager()
+	server.Engine().POST("/api/user/v1/info", secretMgr.InterceptToken(), secretMgr.InterceptAes(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseData(nil)
+	})
+	config := server.Config()
+	token, _ := secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	tokenInfo, _ := secretMgr.ParseToken(token, config.JwtSecret, config.JwtIssuer)
+	key, _ := secretMgr.GetAesKey(tokenInfo.Id)
+	keyBytes, _ := hex.DecodeString(key)
+	request := func(parameter interface{}) float64 {
+		_, resp := serveTestRequest(server, "POST", "/api/user/v1/info", map[string]string{"Authorization": token}, aesTestSecret(t, parameter, keyBytes))
+		return resp["code"].(float64)
+	}
+
+	type userParameter struct {
+		UserName string `json:"user_name"`
+		SecretReplayParams
+	}
+	parameter := userParameter{"seantest1", SecretReplayParams{Timestamp: time.Now().Unix(), Nonce: "nonce-1"}}
+	if code := request(parameter); code != STATUS_CODE_SUCCESS {
+		t.Fatalf("code %v, expected success", code)
+	}
+	// 重放
+	if code := request(parameter); code != STATUS_CODE_SECRET_REPLAYED {
+		t.Errorf("replayed code %v, expected %d", code, STATUS_CODE_SECRET_REPLAYED)
+	}
+	// 时间戳超出窗口
+	parameter = userParameter{"seantest1", SecretReplayParams{Timestamp: time.Now().Add(-2 * time.Minute).Unix(), Nonce: "nonce-2"}}
+	if code := request(parameter); code != STATUS_CODE_SECRET_REPLAYED {
+		t.Errorf("stale code %v, expected %d", code, STATUS_CODE_SECRET_REPLAYED)
+	}
+	// 缺少nonce
+	if code := request(map[string]string{"user_name": "seantest1"}); code != STATUS_CODE_SECRET_CHECK_FAILED {
+		t.Errorf("no nonce code %v, expected %d", code, STATUS_CODE_SECRET_CHECK_FAILED)
+	}
+}
+
+func TestStorageSetNX(t *testing.T) {
+	mr, redisStorage := newTestRedisStorage(t)
+	defer mr.Close()
+	defer redisStorage.Close()
+	dir := newBoltTestDir(t)
+	defer os.RemoveAll(dir)
+	boltStorage, err := NewBoltStorage(BoltStorageConfig{Path: filepath.Join(dir, "secret.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer boltStorage.Close()
+
+	for _, storage := range []ISecretStorageSetNX{NewMemeoryStorageWithConfig(MemoryStorageConfig{}), redisStorage, boltStorage} {
+		if ok, err := storage.SetNX("nonce:seantest1", 1, time.Minute); err != nil || !ok {
+			t.Errorf("%T first set %v, %v", storage, ok, err)
+		}
+		if ok, err := storage.SetNX("nonce:seantest1", 2, time.Minute); err != nil || ok {
+			t.Errorf("%T second set %v, %v", storage, ok, err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package serving
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/sean-tech/gokit/encrypt"
+)
+
+/**
+ * rsa通道解密，参数携带key时为混合加密信封：key为ServerPubKey加密的随机aes key，secret为该key的aes-gcm密文
+ * 未携带key时为整体rsa加密，返回的aesKey为nil
+ */
+func secretRsaDecrypt(serverPriKey string, params SecretParams, encrypted []byte) (jsonBytes []byte, aesKey []byte, err error) {
+	if params.Key == "" {
+		jsonBytes, err = encrypt.GetRsa().Decrypt(serverPriKey, encrypted)
+		return jsonBytes, nil, err
+	}
+	encryptedKey, err := base64.StdEncoding.DecodeString(params.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if aesKey, err = encrypt.GetRsa().Decrypt(serverPriKey, encryptedKey); err != nil {
+		return nil, nil, err
+	}
+	if len(aesKey) != 16 && len(aesKey) != 24 && len(aesKey) != 32 {
+		return nil, nil, errors.New("secret envelope aes key size invalid")
+	}
+	if jsonBytes, err = aesGcmDecrypt(encrypted, aesKey); err != nil {
+		return nil, nil, err
+	}
+	return jsonBytes, aesKey, nil
+}
+
+/**
+ * 按密钥版本解密，未指定版本时依次尝试未退役的服务端密钥
+ */
+func secretRsaDecryptWithKeys(config *HttpConfig, version string, params SecretParams, encrypted []byte) (jsonBytes []byte, aesKey []byte, err error) {
+	keys, err := config.requestServerKeys(version)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, key := range keys {
+		if jsonBytes, aesKey, err = secretRsaDecrypt(key.PriKey, params, encrypted); err == nil {
+			return jsonBytes, aesKey, nil
+		}
+	}
+	return nil, nil, err
+}
+
+/**
+ * rsa通道响应加密，请求为混合加密信封时复用客户端aes key以aes-gcm加密，否则以ClientPubKey整体rsa加密
+ */
+func secretRsaEncrypt(clientPubKey string, aesKey []byte, data []byte) ([]byte, error) {
+	if aesKey != nil {
+		return aesGcmEncrypt(data, aesKey)
+	}
+	return encrypt.GetRsa().Encrypt(clientPubKey, data)
+}
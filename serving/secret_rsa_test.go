@@ -0,0 +1,101 @@
+package serving
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/gin-gonic/gin"
+	"github.com/sean-tech/gokit/encrypt"
+	"strings"
+	"testing"
+)
+
+/**
+ * 测试用rsa密钥对，公钥PKIX，私钥PKCS8
+ */
+func rsaTestKeyPair(t *testing.T) (pubKey string, priKey string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priBytes, _ := x509.MarshalPKCS8PrivateKey(key)
+	pubBytes, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priBytes}))
+}
+
+func TestRsaHybridRequest(t *testing.T) {
+	serverPubKey, serverPriKey := rsaTestKeyPair(t)
+	clientPubKey, clientPriKey := rsaTestKeyPair(t)
+	server := newSecretTestServer(t, HttpConfig{
+		SecretOpen:   true,
+		ServerPubKey: serverPubKey,
+		ServerPriKey: serverPriKey,
+		ClientPubKey: clientPubKey,
+	})
+	server.Engine().POST("/api/user/v1/upload", server.SecretManager().InterceptRsa(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		var parameter map[string]string
+		if err := g.BindParameter(&parameter); err != nil {
+			g.ResponseError(err)
+			return
+		}
+		g.ResponseData(map[string]int{"size": len(parameter["content"])})
+	})
+
+	jsonBytes, _ := json.Marshal(map[string]string{"content": strings.Repeat("webkit", 2000)})
+	signBytes, _ := encrypt.GetRsa().Sign(clientPriKey, jsonBytes)
+	header := map[string]string{"sign": base64.StdEncoding.EncodeToString(signBytes)}
+
+	// 混合加密信封
+	aesKey := encrypt.GetAes().GenerateKey()
+	secret, _ := aesGcmEncrypt(jsonBytes, aesKey)
+	encryptedKey, _ := encrypt.GetRsa().Encrypt(serverPubKey, aesKey)
+	_, resp := serveTestRequest(server, "POST", "/api/user/v1/upload", header, map[string]string{
+		"secret": base64.StdEncoding.EncodeToString(secret),
+		"key":    base64.StdEncoding.EncodeToString(encryptedKey),
+	})
+	if resp["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Fatalf("hybrid code %v, expected success", resp["code"])
+	}
+	encrypted, _ := base64.StdEncoding.DecodeString(resp["data"].(string))
+	decrypted, err := aesGcmDecrypt(encrypted, aesKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decrypted) != `{"size":12000}` {
+		t.Errorf("hybrid response %s", decrypted)
+	}
+	respSign, _ := base64.StdEncoding.DecodeString(resp["sign"].(string))
+	if err := encrypt.GetRsa().Verify(serverPubKey, decrypted, respSign); err != nil {
+		t.Errorf("hybrid response sign verify failed, %v", err)
+	}
+
+	// 整体rsa加密保持兼容
+	secret, _ = encrypt.GetRsa().Encrypt(serverPubKey, jsonBytes)
+	_, resp = serveTestRequest(server, "POST", "/api/user/v1/upload", header, map[string]string{
+		"secret": base64.StdEncoding.EncodeToString(secret),
+	})
+	if resp["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Fatalf("rsa code %v, expected success", resp["code"])
+	}
+	encrypted, _ = base64.StdEncoding.DecodeString(resp["data"].(string))
+	if decrypted, err := encrypt.GetRsa().Decrypt(clientPriKey, encrypted); err != nil || string(decrypted) != `{"size":12000}` {
+		t.Errorf("rsa response %s, %v", decrypted, err)
+	}
+
+	// 签名不匹配
+	otherKey := encrypt.GetAes().GenerateKey()
+	secret, _ = aesGcmEncrypt([]byte(`{"content":"other"}`), otherKey)
+	encryptedKey, _ = encrypt.GetRsa().Encrypt(serverPubKey, otherKey)
+	_, resp = serveTestRequest(server, "POST", "/api/user/v1/upload", header, map[string]string{
+		"secret": base64.StdEncoding.EncodeToString(secret),
+		"key":    base64.StdEncoding.EncodeToString(encryptedKey),
+	})
+	if resp["code"].(float64) != STATUS_CODE_SECRET_CHECK_FAILED {
+		t.Errorf("sign mismatch code %v, expected %d", resp["code"], STATUS_CODE_SECRET_CHECK_FAILED)
+	}
+}
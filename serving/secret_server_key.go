@@ -0,0 +1,112 @@
+package serving
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+)
+
+// 请求加密所用服务端密钥版本请求头，响应头回写签名所用的当前版本
+const HEADER_KEY_VERSION = "X-Key-Version"
+
+/**
+ * 服务端rsa密钥对，按版本轮换
+ */
+type ServerKeyPair struct {
+	Version 	string		`json:"version" validate:"required,gte=1"`
+	PubKey 		string		`json:"pub_key" validate:"required"`
+	PriKey 		string		`json:"pri_key" validate:"required"`
+	// 已退役，不再接受以该公钥加密的请求
+	Retired 	bool		`json:"retired"`
+}
+
+/**
+ * 服务端密钥校验，当前版本须存在且未退役
+ */
+func checkServerKeys(config HttpConfig) error {
+	if len(config.ServerKeys) == 0 {
+		return nil
+	}
+	var versions = make(map[string]bool, len(config.ServerKeys))
+	for _, key := range config.ServerKeys {
+		if versions[key.Version] {
+			return errors.New("server key version " + key.Version + " duplicated")
+		}
+		versions[key.Version] = true
+	}
+	if config.currentServerKey() == nil {
+		return errors.New("server key version " + config.ServerKeyVersion + " not exist or retired")
+	}
+	return nil
+}
+
+/**
+ * 当前服务端密钥，用于响应签名，未配置ServerKeys时为ServerPubKey/ServerPriKey
+ * ServerKeyVersion为空时取第一个未退役的密钥
+ */
+func (this *HttpConfig) currentServerKey() *ServerKeyPair {
+	if len(this.ServerKeys) == 0 {
+		return &ServerKeyPair{PubKey: this.ServerPubKey, PriKey: this.ServerPriKey}
+	}
+	for i := range this.ServerKeys {
+		key := &this.ServerKeys[i]
+		if key.Retired {
+			continue
+		}
+		if this.ServerKeyVersion == "" || this.ServerKeyVersion == key.Version {
+			return key
+		}
+	}
+	return nil
+}
+
+/**
+ * 请求解密可用的服务端密钥，指定版本时仅该版本，否则当前密钥优先、其余未退役密钥依次尝试
+ */
+func (this *HttpConfig) requestServerKeys(version string) ([]*ServerKeyPair, error) {
+	current := this.currentServerKey()
+	if len(this.ServerKeys) == 0 {
+		if version != "" {
+			return nil, errors.New("server key version " + version + " not exist")
+		}
+		return []*ServerKeyPair{current}, nil
+	}
+	if version != "" {
+		for i := range this.ServerKeys {
+			if key := &this.ServerKeys[i]; key.Version == version && !key.Retired {
+				return []*ServerKeyPair{key}, nil
+			}
+		}
+		return nil, errors.New("server key version " + version + " not exist or retired")
+	}
+	var keys = []*ServerKeyPair{current}
+	for i := range this.ServerKeys {
+		if key := &this.ServerKeys[i]; key != current && !key.Retired {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+/**
+ * 服务端当前公钥
+ */
+type ServerPubKeyResult struct {
+	Version 	string		`json:"version"`
+	PubKey 		string		`json:"pubKey"`
+}
+
+/**
+ * 当前服务端公钥接口，客户端据此更新加密公钥及响应验签公钥
+ */
+func (this *secretManagerImpl) ServerPubKeyHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		g := Gin{ctx}
+		config, ok := this.interceptConfig(&g)
+		if !ok {
+			return
+		}
+		current := config.currentServerKey()
+		var code StatusCode = STATUS_CODE_SUCCESS
+		g.Response(code, code.Msg(), ServerPubKeyResult{Version: current.Version, PubKey: current.PubKey}, "")
+	}
+}
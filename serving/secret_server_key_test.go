@@ -0,0 +1,105 @@
+package serving
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/sean-tech/gokit/encrypt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerKeyRotation(t *testing.T) {
+	oldPubKey, oldPriKey := rsaTestKeyPair(t)
+	newPubKey, newPriKey := rsaTestKeyPair(t)
+	retiredPubKey, retiredPriKey := rsaTestKeyPair(t)
+	clientPubKey, clientPriKey := rsaTestKeyPair(t)
+	server := newSecretTestServer(t, HttpConfig{
+		SecretOpen:   true,
+		ClientPubKey: clientPubKey,
+		ServerKeys: []ServerKeyPair{
+			{Version: "v1", PubKey: retiredPubKey, PriKey: retiredPriKey, Retired: true},
+			{Version: "v2", PubKey: oldPubKey, PriKey: oldPriKey},
+			{Version: "v3", PubKey: newPubKey, PriKey: newPriKey},
+		},
+		ServerKeyVersion: "v3",
+	})
+	secretMgr := server.SecretManager()
+	server.Engine().GET("/api/secret/v1/pubkey", secretMgr.ServerPubKeyHandler())
+	server.Engine().POST("/api/user/v1/info", secretMgr.InterceptRsa(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseData(map[string]string{"hello": "world"})
+	})
+	request := func(version string, serverPubKey string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		jsonBytes := []byte(`{"user_name":"seantest1"}`)
+		secret, _ := encrypt.GetRsa().Encrypt(serverPubKey, jsonBytes)
+		signBytes, _ := encrypt.GetRsa().Sign(clientPriKey, jsonBytes)
+		body, _ := json.Marshal(map[string]string{"secret": base64.StdEncoding.EncodeToString(secret)})
+		req := httptest.NewRequest("POST", "/api/user/v1/info", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("sign", base64.StdEncoding.EncodeToString(signBytes))
+		if version != "" {
+			req.Header.Set(HEADER_KEY_VERSION, version)
+		}
+		recorder := httptest.NewRecorder()
+		server.Engine().ServeHTTP(recorder, req)
+		var resp map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &resp)
+		return recorder, resp
+	}
+
+	// 当前公钥
+	_, resp := serveTestRequest(server, "GET", "/api/secret/v1/pubkey", nil, nil)
+	if data := resp["data"].(map[string]interface{}); data["version"] != "v3" || data["pubKey"] != newPubKey {
+		t.Errorf("current pub key %v", data)
+	}
+
+	var cases = []struct {
+		version string
+		pubKey  string
+		code    float64
+	}{
+		{"v3", newPubKey, STATUS_CODE_SUCCESS},
+		{"v2", oldPubKey, STATUS_CODE_SUCCESS},
+		// 未携带版本的旧客户端
+		{"", oldPubKey, STATUS_CODE_SUCCESS},
+		{"v1", retiredPubKey, STATUS_CODE_SECRET_CHECK_FAILED},
+		{"", retiredPubKey, STATUS_CODE_SECRET_CHECK_FAILED},
+		{"v3", oldPubKey, STATUS_CODE_SECRET_CHECK_FAILED},
+	}
+	for _, c := range cases {
+		recorder, resp := request(c.version, c.pubKey)
+		if resp["code"].(float64) != c.code {
+			t.Errorf("version %q code %v, expected %v", c.version, resp["code"], c.code)
+			continue
+		}
+		if c.code != STATUS_CODE_SUCCESS {
+			continue
+		}
+		// 均以当前密钥签名
+		if version := recorder.Header().Get(HEADER_KEY_VERSION); version != "v3" {
+			t.Errorf("response key version %s, expected v3", version)
+		}
+		encrypted, _ := base64.StdEncoding.DecodeString(resp["data"].(string))
+		decrypted, _ := encrypt.GetRsa().Decrypt(clientPriKey, encrypted)
+		signBytes, _ := base64.StdEncoding.DecodeString(resp["sign"].(string))
+		if err := encrypt.GetRsa().Verify(newPubKey, decrypted, signBytes); err != nil {
+			t.Errorf("response sign verify with current key failed, %v", err)
+		}
+	}
+}
+
+func TestServerKeysConfigInvalid(t *testing.T) {
+	pubKey, priKey := rsaTestKeyPair(t)
+	var configs = []HttpConfig{
+		{ServerKeys: []ServerKeyPair{{Version: "v1", PubKey: pubKey, PriKey: priKey}}, ServerKeyVersion: "v2"},
+		{ServerKeys: []ServerKeyPair{{Version: "v1", PubKey: pubKey, PriKey: priKey, Retired: true}}},
+		{ServerKeys: []ServerKeyPair{{Version: "v1", PubKey: pubKey, PriKey: priKey}, {Version: "v1", PubKey: pubKey, PriKey: priKey}}},
+	}
+	for i, config := range configs {
+		if err := checkServerKeys(config); err == nil {
+			t.Errorf("config %d should be invalid", i)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package serving
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/sean-tech/gokit/foundation"
+	"time"
+)
+
+const (
+	// 会话列表锁过期时间，持锁实例异常退出时到期自动释放
+	session_lock_expiration = 5 * time.Second
+	// 会话列表锁等待时长
+	session_lock_wait 		= 3 * time.Second
+	session_lock_retry 		= 10 * time.Millisecond
+)
+
+/**
+ * 登录会话，每次登录生成，id即token的jti
+ */
+type Session struct {
+	Id 			string		`json:"id"`
+	UserId 		uint64		`json:"userId"`
+	UserName 	string		`json:"userName"`
+	CreatedAt 	int64		`json:"createdAt"`
+	ExpiresAt 	int64		`json:"expiresAt"`
+}
+
+/**
+ * 获取用户当前有效会话，按创建时间升序
+ */
+func (this *secretManagerImpl) ListSessions(userName string) ([]*Session, error) {
+	return this.loadSessions(userName)
+}
+
+/**
+ * 注销用户的单个会话
+ */
+func (this *secretManagerImpl) RevokeSession(userName string, sessionId string) error {
+	unlock, err := this.lockSessions(userName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	sessions, err := this.loadSessions(userName)
+	if err != nil {
+		return err
+	}
+	var remains = make([]*Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session.Id != sessionId {
+			remains = append(remains, session)
+		}
+	}
+	if len(remains) == len(sessions) {
+		return foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, "session " + sessionId + " of user " + userName + " not exist")
+	}
+	for _, session := range sessions {
+		if session.Id == sessionId {
+			this.revokeSessionData(session)
+		}
+	}
+	return this.saveSessions(userName, remains)
+}
+
+/**
+ * 注销用户全部会话
+ */
+func (this *secretManagerImpl) RevokeUser(userName string) error {
+	unlock, err := this.lockSessions(userName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	sessions, err := this.loadSessions(userName)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		this.revokeSessionData(session)
+	}
+	this.keyspace.Delete(SECRET_SPACE_SESSIONS, userName)
+	return nil
+}
+
+/**
+ * 注销token所属会话，用于登出
+ */
+func (this *secretManagerImpl) RevokeToken(token string, JwtSecret string, JwtIssuer string) error {
+	tokenInfo, err := this.ParseToken(token, JwtSecret, JwtIssuer)
+	if err != nil {
+		return err
+	}
+	return this.RevokeSession(tokenInfo.UserName, tokenInfo.Id)
+}
+
+/**
+ * 新增会话，超出最大会话数时注销最早的会话
+ */
+func (this *secretManagerImpl) addSession(session *Session) error {
+	unlock, err := this.lockSessions(session.UserName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	sessions, err := this.loadSessions(session.UserName)
+	if err != nil {
+		return err
+	}
+	sessions = append(sessions, session)
+	if this.maxSessions > 0 {
+		for len(sessions) > this.maxSessions {
+			this.revokeSessionData(sessions[0])
+			sessions = sessions[1:]
+		}
+	}
+	return this.saveSessions(session.UserName, sessions)
+}
+
+/**
+ * 移除会话记录，签发失败时回滚新增的会话，不记录注销标记
+ */
+func (this *secretManagerImpl) removeSession(userName string, sessionId string) error {
+	unlock, err := this.lockSessions(userName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	sessions, err := this.loadSessions(userName)
+	if err != nil {
+		return err
+	}
+	var remains = make([]*Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session.Id != sessionId {
+			remains = append(remains, session)
+		}
+	}
+	return this.saveSessions(userName, remains)
+}
+
+/**
+ * 更新会话过期时间，会话不存在返回error
+ */
+func (this *secretManagerImpl) renewSession(userName string, sessionId string, expiresAt int64) error {
+	unlock, err := this.lockSessions(userName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	sessions, err := this.loadSessions(userName)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.Id == sessionId {
+			if expiresAt > session.ExpiresAt {
+				session.ExpiresAt = expiresAt
+			}
+			return this.saveSessions(userName, sessions)
+		}
+	}
+	return foundation.NewError(STATUS_CODE_AUTH_CHECK_TOKEN_FAILED, "session " + sessionId + " of user " + userName + " not exist")
+}
+
+/**
+ * 加用户会话列表锁，锁经存储SetNX写入，多实例共享存储时会话列表读改写互斥
+ * 返回解锁函数，等待超时或存储故障返回error
+ */
+func (this *secretManagerImpl) lockSessions(userName string) (unlock func(), err error) {
+	owner, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	// 本实例内先行互斥，storage未实现ISecretStorageSetNX时仍保证单实例安全
+	this.sessionLock.Lock()
+	deadline := time.Now().Add(session_lock_wait)
+	for {
+		ok, err := this.keyspace.SetNX(SECRET_SPACE_SESSIONS_LOCK, userName, owner, session_lock_expiration)
+		if err != nil {
+			this.sessionLock.Unlock()
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			this.sessionLock.Unlock()
+			return nil, errors.New("session list of user " + userName + " is locked")
+		}
+		time.Sleep(session_lock_retry)
+	}
+	return func() {
+		// 锁已过期并被其他实例持有时不删除
+		if value, err := this.keyspace.Get(SECRET_SPACE_SESSIONS_LOCK, userName); err == nil && value == owner {
+			this.keyspace.Delete(SECRET_SPACE_SESSIONS_LOCK, userName)
+		}
+		this.sessionLock.Unlock()
+	}, nil
+}
+
+/**
+ * 读取会话列表，过滤已过期会话，列表不存在时返回空，存储故障返回error
+ */
+func (this *secretManagerImpl) loadSessions(userName string) ([]*Session, error) {
+	value, err := this.keyspace.Get(SECRET_SPACE_SESSIONS, userName)
+	if IsSecretNotExist(err) {
+		return []*Session{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sessions []*Session
+	if err := json.Unmarshal([]byte(value), &sessions); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	var valid = make([]*Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session.ExpiresAt >= now {
+			valid = append(valid, session)
+		}
+	}
+	return valid, nil
+}
+
+/**
+ * 保存会话列表，列表过期时间取最晚过期的会话
+ */
+func (this *secretManagerImpl) saveSessions(userName string, sessions []*Session) error {
+	if len(sessions) == 0 {
+		this.keyspace.Delete(SECRET_SPACE_SESSIONS, userName)
+		return nil
+	}
+	var expiresAt int64 = 0
+	for _, session := range sessions {
+		if session.ExpiresAt > expiresAt {
+			expiresAt = session.ExpiresAt
+		}
+	}
+	jsonBytes, err := json.Marshal(sessions)
+	if err != nil {
+		return err
+	}
+	expiration := time.Until(time.Unix(expiresAt, 0)) + time.Second
+	return this.keyspace.Set(SECRET_SPACE_SESSIONS, userName, string(jsonBytes), expiration)
+}
+
+/**
+ * 删除会话数据并记录注销标记，标记保留至会话原过期时间，期间token校验返回已注销
+ */
+func (this *secretManagerImpl) revokeSessionData(session *Session) {
+	this.deleteSessionData(session.Id)
+	if expiration := time.Until(time.Unix(session.ExpiresAt, 0)) + time.Second; expiration > 0 {
+		this.keyspace.Set(SECRET_SPACE_REVOKED, session.Id, session.UserName, expiration)
+	}
+}
+
+/**
+ * 删除会话关联的token、aes key及refresh token
+ */
+func (this *secretManagerImpl) deleteSessionData(sessionId string) {
+	this.keyspace.Delete(SECRET_SPACE_TOKEN, sessionId)
+	this.keyspace.Delete(SECRET_SPACE_TOKEN_PREVIOUS, sessionId)
+	this.keyspace.Delete(SECRET_SPACE_AESKEY, sessionId)
+	this.keyspace.Delete(SECRET_SPACE_REFRESH, sessionId)
+}
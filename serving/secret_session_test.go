@@ -0,0 +1,226 @@
+package serving
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/sean-tech/gokit/foundation"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMultiDeviceSessions(t *testing.T) {
+	var secret = "ahsjdadusba"
+	var issuer = "sean.test"
+	secretMgr := NewSecretManager(SecretManagerConfig{})
+
+	webToken, err := secretMgr.GenerateToken(1230090123, "seantest1", false, secret, issuer, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phoneToken, err := secretMgr.GenerateToken(1230090123, "seantest1", false, secret, issuer, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 手机登录不影响web会话
+	if err := secretMgr.CheckToken(webToken, secret, issuer); err != nil {
+		t.Error(err)
+	}
+	if err := secretMgr.CheckToken(phoneToken, secret, issuer); err != nil {
+		t.Error(err)
+	}
+	sessions, err := secretMgr.ListSessions("seantest1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("sessions count %d, expected 2", len(sessions))
+	}
+
+	// 注销web会话
+	webInfo, _ := secretMgr.ParseToken(webToken, secret, issuer)
+	if err := secretMgr.RevokeSession("seantest1", webInfo.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err := secretMgr.CheckToken(webToken, secret, issuer); err == nil {
+		t.Error("revoked session token should be rejected")
+	}
+	if _, err := secretMgr.GetAesKey(webInfo.Id); err == nil {
+		t.Error("revoked session aes key should be deleted")
+	}
+	if err := secretMgr.CheckToken(phoneToken, secret, issuer); err != nil {
+		t.Error(err)
+	}
+	if err := secretMgr.RevokeSession("seantest1", webInfo.Id); err == nil {
+		t.Error("revoke not exist session should return error")
+	} else if e, ok := err.(CError); !ok || e.Code() != STATUS_CODE_AUTH_CHECK_TOKEN_FAILED {
+		t.Errorf("revoke not exist session error %v", err)
+	}
+
+	// 注销全部会话
+	if err := secretMgr.RevokeUser("seantest1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := secretMgr.CheckToken(phoneToken, secret, issuer); err == nil {
+		t.Error("revoked user token should be rejected")
+	}
+	if sessions, _ := secretMgr.ListSessions("seantest1"); len(sessions) != 0 {
+		t.Errorf("sessions count %d, expected 0", len(sessions))
+	}
+}
+
+func TestMaxSessions(t *testing.T) {
+	var secret = "ahsjdadusba"
+	var issuer = "sean.test"
+	secretMgr := NewSecretManager(SecretManagerConfig{MaxSessions: 2})
+
+	var tokens []string
+	for i := 0; i < 3; i++ {
+		token, err := secretMgr.GenerateToken(1230090123, "seantest1", false, secret, issuer, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := secretMgr.CheckToken(tokens[0], secret, issuer); err == nil {
+		t.Error("oldest session should be evicted")
+	}
+	for _, token := range tokens[1:] {
+		if err := secretMgr.CheckToken(token, secret, issuer); err != nil {
+			t.Error(err)
+		}
+	}
+	if sessions, _ := secretMgr.ListSessions("seantest1"); len(sessions) != 2 {
+		t.Errorf("sessions count %d, expected 2", len(sessions))
+	}
+}
+
+func TestSharedStorageSessions(t *testing.T) {
+	var secret = "ahsjdadusba"
+	var issuer = "sean.test"
+	storage := NewMemeoryStorage()
+	// 两个实例共享存储，并发登录同一用户
+	var managers []ISecretManager
+	for i := int64(0); i < 2; i++ {
+		idWorker, _ := foundation.NewWorker(i)
+		managers = append(managers, NewSecretManager(SecretManagerConfig{Storage: storage, IdWorker: idWorker}))
+	}
+	var wait sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wait.Add(1)
+		go func(secretMgr ISecretManager) {
+			defer wait.Done()
+			if _, err := secretMgr.GenerateToken(1230090123, "seantest1", false, secret, issuer, time.Hour); err != nil {
+				t.Error(err)
+			}
+		}(managers[i % 2])
+	}
+	wait.Wait()
+	sessions, err := managers[0].ListSessions("seantest1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 20 {
+		t.Errorf("sessions count %d, expected 20", len(sessions))
+	}
+}
+
+/** 会话列表读取故障的存储 **/
+type sessionsFailedStorage struct {
+	ISecretStorage
+}
+
+func (this *sessionsFailedStorage) Get(key string) (string, error) {
+	if strings.HasPrefix(key, SECRET_SPACE_SESSIONS + ":") {
+		return "", errors.New("storage unavailable")
+	}
+	return this.ISecretStorage.Get(key)
+}
+
+func TestSessionStorageFailed(t *testing.T) {
+	var secret = "ahsjdadusba"
+	var issuer = "sean.test"
+	storage := NewMemeoryStorage()
+	secretMgr := NewSecretManager(SecretManagerConfig{Storage: storage})
+	if _, err := secretMgr.GenerateToken(1230090123, "seantest1", false, secret, issuer, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// 存储故障不得视为空列表而覆盖已有会话
+	failedMgr := NewSecretManager(SecretManagerConfig{Storage: &sessionsFailedStorage{storage}})
+	if _, err := failedMgr.ListSessions("seantest1"); err == nil {
+		t.Error("list sessions should return storage error")
+	}
+	if _, err := failedMgr.GenerateToken(1230090123, "seantest1", false, secret, issuer, time.Hour); err == nil {
+		t.Error("generate token should fail on storage error")
+	}
+	if sessions, _ := secretMgr.ListSessions("seantest1"); len(sessions) != 1 {
+		t.Errorf("sessions count %d, expected 1", len(sessions))
+	}
+}
+
+/** token写入故障的存储 **/
+type tokenFailedStorage struct {
+	ISecretStorage
+}
+
+func (this *tokenFailedStorage) Set(key string, value interface{}, expiration time.Duration) error {
+	if strings.HasPrefix(key, SECRET_SPACE_TOKEN + ":") {
+		return errors.New("storage unavailable")
+	}
+	return this.ISecretStorage.Set(key, value, expiration)
+}
+
+func TestGenerateTokenFailed(t *testing.T) {
+	secretMgr := NewSecretManager(SecretManagerConfig{Storage: &tokenFailedStorage{NewMemeoryStorage()}, MaxSessions: 1})
+	if _, err := secretMgr.GenerateToken(1230090123, "seantest1", false, "ahsjdadusba", "sean.test", time.Hour); err == nil {
+		t.Fatal("generate token should fail on storage error")
+	}
+	// 签发失败的会话不保留
+	if sessions, err := secretMgr.ListSessions("seantest1"); err != nil || len(sessions) != 0 {
+		t.Errorf("sessions %v, %v, expected none", sessions, err)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	server := newSecretTestServer(t, HttpConfig{})
+	secretMgr := server.SecretManager()
+	server.Engine().POST("/api/user/v1/info", secretMgr.InterceptToken(), func(ctx *gin.Context) {
+		g := Gin{ctx}
+		g.ResponseData(nil)
+	})
+
+	config := server.Config()
+	token, err := secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, resp := serveTestRequest(server, "POST", "/api/user/v1/info", map[string]string{"Authorization": token}, nil); resp["code"].(float64) != STATUS_CODE_SUCCESS {
+		t.Fatalf("code %v, expected success", resp["code"])
+	}
+	tokenInfo, _ := secretMgr.ParseToken(token, config.JwtSecret, config.JwtIssuer)
+
+	// logout
+	if err := secretMgr.RevokeToken(token, config.JwtSecret, config.JwtIssuer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := secretMgr.GetAesKey(tokenInfo.Id); err == nil {
+		t.Error("revoked token aes key should be deleted")
+	}
+	if _, resp := serveTestRequest(server, "POST", "/api/user/v1/info", map[string]string{"Authorization": token}, nil); resp["code"].(float64) != STATUS_CODE_AUTH_TOKEN_REVOKED {
+		t.Errorf("code %v, expected %d", resp["code"], STATUS_CODE_AUTH_TOKEN_REVOKED)
+	}
+	if err := secretMgr.RevokeToken(token, config.JwtSecret, config.JwtIssuer); err == nil {
+		t.Error("revoke revoked token should return error")
+	}
+
+	// 封禁用户
+	token, _ = secretMgr.GenerateToken(1230090123, "seantest1", false, config.JwtSecret, config.JwtIssuer, config.JwtExpiresTime)
+	if err := secretMgr.RevokeUser("seantest1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, resp := serveTestRequest(server, "POST", "/api/user/v1/info", map[string]string{"Authorization": token}, nil); resp["code"].(float64) != STATUS_CODE_AUTH_TOKEN_REVOKED {
+		t.Errorf("code %v, expected %d", resp["code"], STATUS_CODE_AUTH_TOKEN_REVOKED)
+	}
+}
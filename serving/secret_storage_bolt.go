@@ -0,0 +1,304 @@
+package serving
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/sean-tech/gokit/validate"
+	bolt "go.etcd.io/bbolt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	default_bolt_storage_bucket 			= "webkit_secret"
+	default_bolt_storage_cleanup_interval 	= time.Minute
+	default_bolt_storage_compact_interval 	= time.Hour
+)
+
+type BoltStorageConfig struct {
+	// 数据文件路径
+	Path 				string			`json:"path" validate:"required,gte=1"`
+	Bucket 				string			`json:"bucket"`
+	// 打开文件等待文件锁超时，为0时一直等待
+	OpenTimeout 		time.Duration	`json:"open_timeout" validate:"gte=0"`
+	// 过期数据清理间隔，为0时使用默认1分钟，小于0时不启动后台清理
+	CleanupInterval 	time.Duration	`json:"cleanup_interval"`
+	// 文件压缩间隔，为0时使用默认1小时，小于0时不启动后台压缩
+	CompactInterval 	time.Duration	`json:"compact_interval"`
+}
+
+/**
+* 获取本地文件存储实例，基于bbolt，重启后token及密钥仍有效
+*/
+func NewBoltStorage(config BoltStorageConfig) (*SecretBoltStorageImpl, error) {
+	if err := validate.ValidateParameter(config); err != nil {
+		return nil, err
+	}
+	if config.Bucket == "" {
+		config.Bucket = default_bolt_storage_bucket
+	}
+	if config.CleanupInterval == 0 {
+		config.CleanupInterval = default_bolt_storage_cleanup_interval
+	}
+	if config.CompactInterval == 0 {
+		config.CompactInterval = default_bolt_storage_compact_interval
+	}
+	this := &SecretBoltStorageImpl{
+		config: config,
+		bucket: []byte(config.Bucket),
+		stop:   make(chan struct{}),
+	}
+	var err error
+	if this.db, err = this.open(config.Path); err != nil {
+		return nil, err
+	}
+	if config.CleanupInterval > 0 || config.CompactInterval > 0 {
+		this.wait.Add(1)
+		go this.janitor()
+	}
+	return this, nil
+}
+
+// 本地文件存储实现，值前8字节记录过期时间(unix纳秒，0为不过期)
+type SecretBoltStorageImpl struct {
+	config 		BoltStorageConfig
+	bucket 		[]byte
+	// 读写操作持读锁，压缩替换文件时持写锁
+	lock 		sync.RWMutex
+	db 			*bolt.DB
+	stopOnce 	sync.Once
+	stop 		chan struct{}
+	wait 		sync.WaitGroup
+}
+
+func (this *SecretBoltStorageImpl) open(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: this.config.OpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(this.bucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func encodeBoltValue(value string, expiresTime time.Duration) []byte {
+	var expiresAt int64 = 0
+	if expiresTime > 0 {
+		expiresAt = time.Now().Add(expiresTime).UnixNano()
+	}
+	data := make([]byte, 8 + len(value))
+	binary.BigEndian.PutUint64(data, uint64(expiresAt))
+	copy(data[8:], value)
+	return data
+}
+
+func boltValueExpired(data []byte, now int64) bool {
+	if len(data) < 8 {
+		return true
+	}
+	expiresAt := int64(binary.BigEndian.Uint64(data))
+	return expiresAt != 0 && now > expiresAt
+}
+
+func (this *SecretBoltStorageImpl) Set(key string, value interface{}, expiresTime time.Duration) error {
+	data := encodeBoltValue(fmt.Sprintf("%v", value), expiresTime)
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(this.bucket).Put([]byte(key), data)
+	})
+}
+
+/**
+ * 键不存在或已过期时写入，返回是否写入
+ */
+func (this *SecretBoltStorageImpl) SetNX(key string, value interface{}, expiresTime time.Duration) (bool, error) {
+	var stored = false
+	data := encodeBoltValue(fmt.Sprintf("%v", value), expiresTime)
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	err := this.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(this.bucket)
+		if exist := bucket.Get([]byte(key)); exist != nil && !boltValueExpired(exist, time.Now().UnixNano()) {
+			return nil
+		}
+		stored = true
+		return bucket.Put([]byte(key), data)
+	})
+	if err != nil {
+		return false, err
+	}
+	return stored, nil
+}
+
+func (this *SecretBoltStorageImpl) Get(key string) (value string, err error) {
+	var exist = false
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	err = this.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(this.bucket).Get([]byte(key))
+		if data == nil || boltValueExpired(data, time.Now().UnixNano()) {
+			return nil
+		}
+		value = string(data[8:])
+		exist = true
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if !exist {
+		return "", &SecretNotExistError{Key: key}
+	}
+	return value, nil
+}
+
+func (this *SecretBoltStorageImpl) Delete(key string) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	this.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(this.bucket).Delete([]byte(key))
+	})
+}
+
+/**
+ * 清理过期数据
+ */
+func (this *SecretBoltStorageImpl) DeleteExpired() error {
+	now := time.Now().UnixNano()
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(this.bucket)
+		var expiredKeys [][]byte
+		bucket.ForEach(func(k, v []byte) error {
+			if boltValueExpired(v, now) {
+				expiredKeys = append(expiredKeys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		for _, key := range expiredKeys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+/**
+ * 文件压缩，仅将未过期数据复制至新文件后替换，回收删除数据占用的空间
+ */
+func (this *SecretBoltStorageImpl) Compact() error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	tmpPath := this.config.Path + ".compact"
+	os.Remove(tmpPath)
+	dst, err := this.open(tmpPath)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixNano()
+	err = this.db.View(func(tx *bolt.Tx) error {
+		return dst.Update(func(dstTx *bolt.Tx) error {
+			dstBucket := dstTx.Bucket(this.bucket)
+			return tx.Bucket(this.bucket).ForEach(func(k, v []byte) error {
+				if boltValueExpired(v, now) {
+					return nil
+				}
+				return dstBucket.Put(append([]byte(nil), k...), append([]byte(nil), v...))
+			})
+		})
+	})
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := this.db.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	// 原文件先备份，替换或打开失败时恢复，始终重新打开后才替换this.db
+	backupPath := this.config.Path + ".bak"
+	backedUp := false
+	restore := func(cause error) error {
+		os.Remove(tmpPath)
+		if backedUp {
+			os.Rename(backupPath, this.config.Path)
+		}
+		if db, err := this.open(this.config.Path); err == nil {
+			this.db = db
+		}
+		return cause
+	}
+	if err := os.Rename(this.config.Path, backupPath); err != nil {
+		return restore(err)
+	}
+	backedUp = true
+	if err := os.Rename(tmpPath, this.config.Path); err != nil {
+		return restore(err)
+	}
+	db, err := this.open(this.config.Path)
+	if err != nil {
+		return restore(err)
+	}
+	os.Remove(backupPath)
+	this.db = db
+	return nil
+}
+
+func (this *SecretBoltStorageImpl) janitor() {
+	defer this.wait.Done()
+	var cleanup, compact <-chan time.Time
+	if this.config.CleanupInterval > 0 {
+		ticker := time.NewTicker(this.config.CleanupInterval)
+		defer ticker.Stop()
+		cleanup = ticker.C
+	}
+	if this.config.CompactInterval > 0 {
+		ticker := time.NewTicker(this.config.CompactInterval)
+		defer ticker.Stop()
+		compact = ticker.C
+	}
+	for {
+		select {
+		case <- cleanup:
+			if err := this.DeleteExpired(); err != nil {
+				log.Printf("bolt storage delete expired error : %v", err)
+			}
+		case <- compact:
+			if err := this.DeleteExpired(); err != nil {
+				log.Printf("bolt storage delete expired error : %v", err)
+			}
+			if err := this.Compact(); err != nil {
+				log.Printf("bolt storage compact error : %v", err)
+			}
+		case <- this.stop:
+			return
+		}
+	}
+}
+
+/**
+ * 停止后台清理并关闭文件
+ */
+func (this *SecretBoltStorageImpl) Close() error {
+	this.stopOnce.Do(func() {
+		close(this.stop)
+	})
+	this.wait.Wait()
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.db.Close()
+}
@@ -0,0 +1,116 @@
+package serving
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newBoltTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "webkit_bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestBoltStorage(t *testing.T) {
+	dir := newBoltTestDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secret.db")
+	storage, err := NewBoltStorage(BoltStorageConfig{Path: path, CleanupInterval: -1, CompactInterval: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.Set("token:seantest1", "tokenvalue", time.Hour)
+	storage.Set("aeskey:seantest1", "keyvalue", 20 * time.Millisecond)
+	storage.Set("deleted", "1", 0)
+	storage.Delete("deleted")
+	if _, err := storage.Get("deleted"); err == nil {
+		t.Error("deleted value should not exist")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, err := storage.Get("aeskey:seantest1"); err == nil {
+		t.Error("expired value should be hidden")
+	}
+	if err := storage.DeleteExpired(); err != nil {
+		t.Error(err)
+	}
+	if err := storage.Compact(); err != nil {
+		t.Error(err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Error(err)
+	}
+
+	// 重启后数据仍在
+	storage, err = NewBoltStorage(BoltStorageConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	if value, err := storage.Get("token:seantest1"); err != nil || value != "tokenvalue" {
+		t.Errorf("get %s, %v", value, err)
+	}
+}
+
+func TestBoltStorageCompactFailed(t *testing.T) {
+	dir := newBoltTestDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secret.db")
+	storage, err := NewBoltStorage(BoltStorageConfig{Path: path, CleanupInterval: -1, CompactInterval: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	storage.Set("token:seantest1", "tokenvalue", time.Hour)
+
+	// 备份路径被占用，替换失败后仍可读写原文件
+	os.MkdirAll(filepath.Join(path + ".bak", "occupied"), 0700)
+	if err := storage.Compact(); err == nil {
+		t.Error("compact should fail when backup path is occupied")
+	}
+	if value, err := storage.Get("token:seantest1"); err != nil || value != "tokenvalue" {
+		t.Errorf("get after failed compact %s, %v", value, err)
+	}
+	if err := storage.Set("token:seantest2", "tokenvalue", time.Hour); err != nil {
+		t.Errorf("set after failed compact, %v", err)
+	}
+}
+
+func TestBoltStorageConcurrent(t *testing.T) {
+	dir := newBoltTestDir(t)
+	defer os.RemoveAll(dir)
+	storage, err := NewBoltStorage(BoltStorageConfig{
+		Path:            filepath.Join(dir, "secret.db"),
+		CleanupInterval: 5 * time.Millisecond,
+		CompactInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	secretMgr := NewSecretManager(SecretManagerConfig{Storage: storage})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userName := "seantest" + string(rune('a' + i))
+			token, err := secretMgr.GenerateToken(uint64(i + 1), userName, false, "ahsjdadusba", "sean.test", time.Hour)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			time.Sleep(15 * time.Millisecond)
+			if err := secretMgr.CheckToken(token, "ahsjdadusba", "sean.test"); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
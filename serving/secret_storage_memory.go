@@ -0,0 +1,212 @@
+package serving
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const default_memory_storage_cleanup_interval = time.Minute
+
+type MemoryStorageConfig struct {
+	// 过期数据清理，为0时于写入时每1分钟顺带清理，大于0时启动后台定期清理(需调用Stop停止)，小于0时仅读取时惰性清理
+	CleanupInterval 	time.Duration	`json:"cleanup_interval"`
+	// 最大存储条数，超出时淘汰最近最少使用的数据，为0时不限制
+	// 经SetNX写入的数据(防重放nonce、刷新令牌消费标记、锁等)不计入且不被淘汰，由各自过期时间回收
+	MaxEntries 			int 			`json:"max_entries" validate:"min=0"`
+}
+
+/**
+* 获取内存存储实例，默认配置，不启动后台协程，需具体类型(如SetNX、Stop)时使用NewMemeoryStorageWithConfig
+*/
+func NewMemeoryStorage() ISecretStorage {
+	return NewMemeoryStorageWithConfig(MemoryStorageConfig{})
+}
+
+/**
+* 获取内存存储实例
+*/
+func NewMemeoryStorageWithConfig(config MemoryStorageConfig) *SecretMemeoryStorageImpl {
+	this := &SecretMemeoryStorageImpl{
+		maxEntries: config.MaxEntries,
+		entries:    make(map[string]*memoryStorageEntry),
+		lru:        list.New(),
+		stop:       make(chan struct{}),
+		now:        time.Now,
+	}
+	if config.CleanupInterval == 0 {
+		this.sweepInterval = default_memory_storage_cleanup_interval
+	}
+	if config.CleanupInterval > 0 {
+		go this.janitor(config.CleanupInterval)
+	}
+	return this
+}
+
+type memoryStorageEntry struct {
+	key 		string
+	value 		string
+	expiresAt 	time.Time
+	// lru节点，SetNX写入的数据为nil，不参与淘汰
+	element 	*list.Element
+}
+
+func (this *memoryStorageEntry) expired(now time.Time) bool {
+	return !this.expiresAt.IsZero() && now.After(this.expiresAt)
+}
+
+// 内存存储实现，按条目记录过期时间，Get时惰性隐藏过期数据，写入时或后台定期清理
+type SecretMemeoryStorageImpl struct {
+	lock 			sync.Mutex
+	maxEntries 		int
+	entries 		map[string]*memoryStorageEntry
+	lru 			*list.List
+	stopOnce 		sync.Once
+	stop 			chan struct{}
+	// 写入时顺带清理的间隔，为0时不清理
+	sweepInterval 	time.Duration
+	lastSweep 		time.Time
+	// 时钟，测试时可替换
+	now 			func() time.Time
+}
+
+func (this *SecretMemeoryStorageImpl) Set(key string, value interface{}, expiresTime time.Duration) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.set(key, value, expiresTime, true)
+	return nil
+}
+
+/**
+ * 键不存在或已过期时写入，返回是否写入，写入数据不参与lru淘汰
+ */
+func (this *SecretMemeoryStorageImpl) SetNX(key string, value interface{}, expiresTime time.Duration) (bool, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if entry, ok := this.entries[key]; ok && !entry.expired(this.now()) {
+		return false, nil
+	}
+	this.set(key, value, expiresTime, false)
+	return true, nil
+}
+
+func (this *SecretMemeoryStorageImpl) set(key string, value interface{}, expiresTime time.Duration, evictable bool) {
+	now := this.now()
+	this.sweep(now)
+	var entry = &memoryStorageEntry{
+		key:   key,
+		value: fmt.Sprintf("%v", value),
+	}
+	if expiresTime > 0 {
+		entry.expiresAt = now.Add(expiresTime)
+	}
+	if old, ok := this.entries[key]; ok {
+		this.remove(old)
+	}
+	this.entries[key] = entry
+	if !evictable {
+		return
+	}
+	entry.element = this.lru.PushFront(entry)
+	if this.maxEntries > 0 {
+		for this.lru.Len() > this.maxEntries {
+			this.remove(this.lru.Back().Value.(*memoryStorageEntry))
+		}
+	}
+}
+
+func (this *SecretMemeoryStorageImpl) Get(key string) (value string, err error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if entry, ok := this.entries[key]; ok {
+		if !entry.expired(this.now()) {
+			if entry.element != nil {
+				this.lru.MoveToFront(entry.element)
+			}
+			return entry.value, nil
+		}
+		this.remove(entry)
+	}
+	return "", &SecretNotExistError{Key: key}
+}
+
+func (this *SecretMemeoryStorageImpl) Delete(key string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if entry, ok := this.entries[key]; ok {
+		this.remove(entry)
+	}
+}
+
+/**
+ * 当前存储条数，含未清理的过期数据
+ */
+func (this *SecretMemeoryStorageImpl) Len() int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return len(this.entries)
+}
+
+/**
+ * 停止后台清理，可重复调用
+ */
+func (this *SecretMemeoryStorageImpl) Stop() {
+	this.stopOnce.Do(func() {
+		close(this.stop)
+	})
+}
+
+/**
+ * 清理过期数据
+ */
+func (this *SecretMemeoryStorageImpl) DeleteExpired() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.deleteExpired(this.now())
+}
+
+func (this *SecretMemeoryStorageImpl) deleteExpired(now time.Time) {
+	for _, entry := range this.entries {
+		if entry.expired(now) {
+			this.remove(entry)
+		}
+	}
+	this.lastSweep = now
+}
+
+/**
+ * 写入时顺带清理，距上次清理超过sweepInterval时执行
+ */
+func (this *SecretMemeoryStorageImpl) sweep(now time.Time) {
+	if this.sweepInterval <= 0 {
+		return
+	}
+	if this.lastSweep.IsZero() {
+		this.lastSweep = now
+		return
+	}
+	if now.Sub(this.lastSweep) >= this.sweepInterval {
+		this.deleteExpired(now)
+	}
+}
+
+func (this *SecretMemeoryStorageImpl) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <- ticker.C:
+			this.DeleteExpired()
+		case <- this.stop:
+			return
+		}
+	}
+}
+
+func (this *SecretMemeoryStorageImpl) remove(entry *memoryStorageEntry) {
+	if entry.element != nil {
+		this.lru.Remove(entry.element)
+	}
+	delete(this.entries, entry.key)
+}
@@ -0,0 +1,115 @@
+package serving
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+/**
+ * 测试时钟，Add推进时间
+ */
+type memoryTestClock struct {
+	now time.Time
+}
+
+func (this *memoryTestClock) Now() time.Time {
+	return this.now
+}
+
+func (this *memoryTestClock) Add(d time.Duration) {
+	this.now = this.now.Add(d)
+}
+
+func newMemoryTestStorage(config MemoryStorageConfig) (*SecretMemeoryStorageImpl, *memoryTestClock) {
+	clock := &memoryTestClock{now: time.Date(2020, 4, 27, 0, 0, 0, 0, time.UTC)}
+	storage := NewMemeoryStorageWithConfig(config)
+	storage.now = clock.Now
+	return storage, clock
+}
+
+func TestMemoryStorageSetReturnsImmediately(t *testing.T) {
+	storage := NewMemeoryStorage()
+
+	begin := time.Now()
+	if err := storage.Set("token:seantest1", "tokenvalue", 36 * time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(begin) > time.Second {
+		t.Error("set should not block until expired")
+	}
+	if value, err := storage.Get("token:seantest1"); err != nil || value != "tokenvalue" {
+		t.Errorf("get %s, %v", value, err)
+	}
+	storage.Delete("token:seantest1")
+	if _, err := storage.Get("token:seantest1"); err == nil {
+		t.Error("deleted value should not exist")
+	}
+}
+
+func TestMemoryStorageExpire(t *testing.T) {
+	storage, clock := newMemoryTestStorage(MemoryStorageConfig{CleanupInterval: -1})
+
+	storage.Set("lazy", "1", 20 * time.Millisecond)
+	storage.Set("forever", "2", 0)
+	storage.Set("purged", "3", 10 * time.Millisecond)
+	clock.Add(30 * time.Millisecond)
+	if _, err := storage.Get("lazy"); err == nil {
+		t.Error("expired value should be hidden")
+	}
+	if storage.Len() != 2 {
+		t.Errorf("expired value not read should remain until cleanup, len %d", storage.Len())
+	}
+	storage.DeleteExpired()
+	if storage.Len() != 1 {
+		t.Errorf("delete expired should purge expired values, len %d", storage.Len())
+	}
+	if value, err := storage.Get("forever"); err != nil || value != "2" {
+		t.Errorf("get %s, %v", value, err)
+	}
+}
+
+func TestMemoryStorageSweepOnWrite(t *testing.T) {
+	storage, clock := newMemoryTestStorage(MemoryStorageConfig{})
+
+	storage.Set("a", "1", time.Second)
+	storage.Set("b", "2", time.Hour)
+	clock.Add(30 * time.Second)
+	storage.Set("c", "3", time.Hour)
+	if storage.Len() != 3 {
+		t.Errorf("should not sweep within interval, len %d", storage.Len())
+	}
+	clock.Add(default_memory_storage_cleanup_interval)
+	storage.Set("d", "4", time.Hour)
+	if storage.Len() != 3 {
+		t.Errorf("write should sweep expired values after interval, len %d", storage.Len())
+	}
+}
+
+func TestMemoryStorageLRU(t *testing.T) {
+	storage, _ := newMemoryTestStorage(MemoryStorageConfig{CleanupInterval: -1, MaxEntries: 2})
+	storage.Set("a", "1", time.Minute)
+	storage.Set("b", "2", time.Minute)
+	storage.Get("a")
+	storage.Set("c", "3", time.Minute)
+	if _, err := storage.Get("b"); err == nil {
+		t.Error("least recently used value should be evicted")
+	}
+	if _, err := storage.Get("a"); err != nil {
+		t.Error(err)
+	}
+	if _, err := storage.Get("c"); err != nil {
+		t.Error(err)
+	}
+
+	// SetNX数据不挤占lru
+	for i := 0; i < 10; i++ {
+		storage.SetNX(fmt.Sprintf("nonce:%d", i), 1, time.Minute)
+	}
+	if _, err := storage.Get("a"); err != nil {
+		t.Errorf("setnx values should not evict lru values, %v", err)
+	}
+	if _, err := storage.Get("nonce:0"); err != nil {
+		t.Errorf("setnx values should not be evicted, %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package serving
+
+import (
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/sean-tech/gokit/validate"
+	"time"
+)
+
+type RedisStorageConfig struct {
+	Addr 			string			`json:"addr" validate:"required,gte=1"`
+	Password 		string			`json:"password"`
+	Db 				int				`json:"db" validate:"min=0"`
+	// 存储键前缀，多个服务共用同一redis时区分
+	KeyPrefix 		string			`json:"key_prefix"`
+	// 连接池
+	PoolSize 		int				`json:"pool_size" validate:"min=0"`
+	MinIdleConns 	int				`json:"min_idle_conns" validate:"min=0"`
+	IdleTimeout 	time.Duration	`json:"idle_timeout" validate:"gte=0"`
+	// 超时
+	DialTimeout 	time.Duration	`json:"dial_timeout" validate:"gte=0"`
+	ReadTimeout 	time.Duration	`json:"read_timeout" validate:"gte=0"`
+	WriteTimeout 	time.Duration	`json:"write_timeout" validate:"gte=0"`
+}
+
+/**
+* 获取redis存储实例，多副本部署时共享token及密钥，创建时校验连接
+*/
+func NewRedisStorage(config RedisStorageConfig) (*SecretRedisStorageImpl, error) {
+	if err := validate.ValidateParameter(config); err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:         config.Addr,
+		Password:     config.Password,
+		DB:           config.Db,
+		PoolSize:     config.PoolSize,
+		MinIdleConns: config.MinIdleConns,
+		IdleTimeout:  config.IdleTimeout,
+		DialTimeout:  config.DialTimeout,
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+	})
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &SecretRedisStorageImpl{
+		client: client,
+		prefix: config.KeyPrefix,
+	}, nil
+}
+
+// redis存储实现，过期时间交由redis管理
+type SecretRedisStorageImpl struct {
+	client *redis.Client
+	prefix string
+}
+
+func (this *SecretRedisStorageImpl) Set(key string, value interface{}, expiresTime time.Duration) error {
+	if expiresTime < 0 {
+		expiresTime = 0
+	}
+	return this.client.Set(this.prefix + key, fmt.Sprintf("%v", value), expiresTime).Err()
+}
+
+/**
+ * 键不存在时写入，返回是否写入
+ */
+func (this *SecretRedisStorageImpl) SetNX(key string, value interface{}, expiresTime time.Duration) (bool, error) {
+	if expiresTime < 0 {
+		expiresTime = 0
+	}
+	return this.client.SetNX(this.prefix + key, fmt.Sprintf("%v", value), expiresTime).Result()
+}
+
+func (this *SecretRedisStorageImpl) Get(key string) (value string, err error) {
+	value, err = this.client.Get(this.prefix + key).Result()
+	if err == redis.Nil {
+		return "", &SecretNotExistError{Key: key}
+	}
+	return value, err
+}
+
+func (this *SecretRedisStorageImpl) Delete(key string) {
+	this.client.Del(this.prefix + key)
+}
+
+/**
+ * 关闭连接池
+ */
+func (this *SecretRedisStorageImpl) Close() error {
+	return this.client.Close()
+}
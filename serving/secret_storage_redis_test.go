@@ -0,0 +1,80 @@
+package serving
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"testing"
+	"time"
+)
+
+func newTestRedisStorage(t *testing.T) (*miniredis.Miniredis, *SecretRedisStorageImpl) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage, err := NewRedisStorage(RedisStorageConfig{
+		Addr:         mr.Addr(),
+		KeyPrefix:    "webkit:",
+		PoolSize:     4,
+		DialTimeout:  time.Second,
+		ReadTimeout:  time.Second,
+		WriteTimeout: time.Second,
+	})
+	if err != nil {
+		mr.Close()
+		t.Fatal(err)
+	}
+	return mr, storage
+}
+
+func TestRedisStorage(t *testing.T) {
+	mr, storage := newTestRedisStorage(t)
+	defer mr.Close()
+	defer storage.Close()
+
+	if err := storage.Set("token:seantest1", "tokenvalue", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := storage.Get("token:seantest1"); err != nil || value != "tokenvalue" {
+		t.Errorf("get %s, %v", value, err)
+	}
+	if !mr.Exists("webkit:token:seantest1") {
+		t.Error("key prefix not applied")
+	}
+	if ttl := mr.TTL("webkit:token:seantest1"); ttl != time.Hour {
+		t.Errorf("ttl %v, expected 1h", ttl)
+	}
+
+	mr.FastForward(2 * time.Hour)
+	if _, err := storage.Get("token:seantest1"); err == nil {
+		t.Error("expired value should not exist")
+	}
+
+	storage.Set("aeskey:seantest1", "keyvalue", 0)
+	storage.Delete("aeskey:seantest1")
+	if _, err := storage.Get("aeskey:seantest1"); err == nil {
+		t.Error("deleted value should not exist")
+	}
+}
+
+func TestRedisStorageSecretManager(t *testing.T) {
+	mr, storage := newTestRedisStorage(t)
+	defer mr.Close()
+	defer storage.Close()
+
+	// 同一redis上的两个manager模拟多副本
+	issuer := NewSecretManager(SecretManagerConfig{Storage: storage, KeyPrefix: "app1"})
+	verifier := NewSecretManager(SecretManagerConfig{Storage: storage, KeyPrefix: "app1"})
+	token, err := issuer.GenerateToken(1230090123, "seantest1", false, "ahsjdadusba", "sean.test", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.CheckToken(token, "ahsjdadusba", "sean.test"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRedisStorageConnectFailed(t *testing.T) {
+	if _, err := NewRedisStorage(RedisStorageConfig{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond}); err == nil {
+		t.Error("unreachable redis should return error")
+	}
+}